@@ -1,29 +1,61 @@
 package checker
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
+	"path"
 	"reflect"
 	"regexp"
 	"slices"
 	"strings"
 	"sync"
+	"time"
+	"unicode"
 
 	"golang.org/x/tools/go/analysis"
 )
 
+// name is the analyzer name under which the checker reports its own
+// diagnostics, such as unused //ignore directives.
+const name = "checker"
+
+// doc is the documentation of the analyzer returned by NewAnalyzer.
+const doc = "runs multiple analyzers and filters diagnostics based on " +
+	"//ignore directives"
+
 // NewAnalyzer creates a new analyzer that runs multiple analyzers and filters
 // diagnostics based on //ignore directives.
+//
+// An //ignore directive that suppresses no diagnostics is reported as a
+// diagnostic of its own, provided every analyzer it names was run. So is a
+// directive that names an analyzer which is neither one of analyzers nor
+// among their requirements, nor in [Config.KnownAnalyzers].
+//
+// Drivers such as go vet analyze a package apart from its test build, so a
+// directive used in only one of them is reported as unused by the other.
+// [Run] reports a directive only if it is unused in both.
 func NewAnalyzer(analyzers ...*analysis.Analyzer) *analysis.Analyzer {
+	return Config{}.NewAnalyzer(analyzers...)
+}
+
+// NewAnalyzer creates a new analyzer that runs multiple analyzers and filters
+// diagnostics based on //ignore directives, as configured by c.
+func (c Config) NewAnalyzer(analyzers ...*analysis.Analyzer) *analysis.Analyzer {
+	if c.ChangedSince != "" {
+		c.changed = sync.OnceValues(func() (changedLines, error) {
+			return gitChangedLines(c.ChangedSince)
+		})
+	}
 	return &analysis.Analyzer{
-		Name: "checker",
-		Doc: "runs multiple analyzers and filters diagnostics based on " +
-			"//ignore directives",
-		FactTypes: factTypes(analyzers),
+		Name:       name,
+		Doc:        doc,
+		FactTypes:  factTypes(analyzers),
+		ResultType: reflect.TypeFor[*result](),
 		Run: func(pass *analysis.Pass) (any, error) {
-			return runAnalyzers(pass, analyzers)
+			return c.runAnalyzers(pass, analyzers)
 		},
 	}
 }
@@ -61,16 +93,56 @@ func factTypes(analyzers []*analysis.Analyzer) (facts []analysis.Fact) {
 
 type ignoreRange struct {
 	start, end token.Pos
-	analyzers  map[string]struct{}
+	directive
+
+	pos  token.Pos // Position of the directive, if any.
+	used bool      // Whether the range suppressed a diagnostic.
+}
+
+// A directive is a parsed //ignore comment, or a comment in one of the
+// foreign syntaxes enabled by [Config].
+type directive struct {
+	kind      string    // Directive prefix, such as "//ignore".
+	reason    string    // Justification, such as text following "--".
+	until     time.Time // Date following "until=", if any.
+	in        bool      // Whether the directive follows other comment text.
+	file      bool      // Whether the directive applies to the whole file.
+	pkg       bool      // Whether the directive applies to the package.
+	begin     bool      // Whether the directive opens a region.
+	end       bool      // Whether the directive closes a region.
+	err       error     // Why the directive is malformed, if it is.
+	selectors []selector
+}
+
+// A selector matches the diagnostics of an analyzer, or of all analyzers,
+// optionally narrowed by category and message.
+type selector struct {
+	analyzer string         // Analyzer name, glob pattern, or "all".
+	category string         // Diagnostic category, if any.
+	message  *regexp.Regexp // Diagnostic message pattern, if any.
+	exclude  bool           // Whether matching diagnostics are excluded.
 }
 
-func runAnalyzers(pass *analysis.Pass, analyzers []*analysis.Analyzer) (any, error) {
+func (c Config) runAnalyzers(pass *analysis.Pass, analyzers []*analysis.Analyzer) (any, error) {
 	// Most of this structure is borrowed from unitchecker.
 
 	if err := detectCycles(analyzers); err != nil {
 		return nil, err
 	}
-	ranges := ignoreRanges(pass)
+	if err := detectGroupCycles(c.Groups); err != nil {
+		return nil, err
+	}
+	rules, err := c.compileRules()
+	if err != nil {
+		return nil, err
+	}
+	ranges, problems := c.ignoreRanges(pass, rules)
+	var changed changedLines
+	if c.changed != nil {
+		if changed, err = c.changed(); err != nil {
+			return nil, err
+		}
+	}
 	var factMu sync.Mutex
 
 	type action struct {
@@ -95,6 +167,16 @@ func runAnalyzers(pass *analysis.Pass, analyzers []*analysis.Analyzer) (any, err
 	for _, a := range analyzers {
 		initActions(a)
 	}
+	known := make(map[string]struct{})
+	for a := range actions {
+		known[a.Name] = struct{}{}
+	}
+	for _, name := range c.KnownAnalyzers {
+		known[name] = struct{}{}
+	}
+	for _, r := range ranges {
+		problems = append(problems, unknownNames(r, known)...)
+	}
 
 	// Execute analyzers on-demand with dependency resolution.
 	var exec func(a *analysis.Analyzer) *action
@@ -207,90 +289,321 @@ func runAnalyzers(pass *analysis.Pass, analyzers []*analysis.Analyzer) (any, err
 			return nil, act.err
 		}
 	}
+	res := new(result)
+	report := func(analyzer string, sev severity, d analysis.Diagnostic) {
+		if unchangedDiagnostic(&d, changed, pass.Fset) {
+			return
+		}
+		res.diags = append(res.diags, reported{analyzer, sev, d})
+		if analyzer != name {
+			d = c.Tag.apply(analyzer, d)
+		}
+		pass.Report(d)
+	}
+	ran := make(map[string]struct{})
 	for analyzer, act := range actions {
 		if act.err != nil {
 			continue
 		}
-		for d := range filter(act.diags, ranges, analyzer.Name, pass.Fset) {
-			pass.Report(d)
+		ran[analyzer.Name] = struct{}{}
+		sev := c.severityOf(analyzer.Name)
+		for _, d := range act.diags {
+			by := ignoreDiagnostic(&d, ranges, analyzer.Name, pass.Fset)
+			if len(by) == 0 {
+				report(analyzer.Name, sev, d)
+			} else if !unchangedDiagnostic(&d, changed, pass.Fset) {
+				res.suppressed = append(res.suppressed, suppressed{
+					reported{analyzer.Name, sev, d}, by,
+				})
+			}
 		}
 	}
-	return nil, nil
+	for _, d := range problems {
+		report(name, severityError, d)
+	}
+	// A directive may be used in another build of the package, such as its
+	// test build, so unused directives are kept apart in res for [Run] to
+	// reconcile.
+	for _, r := range unusedRanges(ranges, ran) {
+		d := analysis.Diagnostic{
+			Pos:      r.pos,
+			Category: name,
+			Message:  fmt.Sprintf("unused %s directive", r.kind),
+		}
+		if unchangedDiagnostic(&d, changed, pass.Fset) {
+			continue
+		}
+		res.unused = append(res.unused, reported{name, severityError, d})
+		pass.Report(d)
+	}
+	if c.RequireReason {
+		for _, r := range ranges {
+			if r.pos.IsValid() && r.reason == "" {
+				report(name, severityError, analysis.Diagnostic{
+					Pos:      r.pos,
+					Category: name,
+					Message:  r.kind + " directive has no reason",
+				})
+			}
+		}
+	}
+	return res, nil
+}
+
+// A result is the result of the combined analyzer on a package. It keeps
+// the analyzer that produced each diagnostic, which pass.Report loses.
+type result struct {
+	diags      []reported
+	suppressed []suppressed
+	unused     []reported // Directives that suppressed nothing.
+}
+
+// A reported diagnostic is one that survived filtering, along with the name
+// of the analyzer that produced it and its severity.
+type reported struct {
+	analyzer string
+	severity severity
+	analysis.Diagnostic
+}
+
+// A suppressed diagnostic is one that was filtered out, along with the
+// directives and rules that filtered it.
+type suppressed struct {
+	reported
+	by []suppression
+}
+
+// A suppression describes a directive or rule that filtered a diagnostic.
+type suppression struct {
+	kind   string // Kind of directive, rulesFile, or "" for a generated file.
+	reason string
 }
 
-func ignoreRanges(pass *analysis.Pass) (ranges []ignoreRange) {
+// unusedRanges returns the directive ranges that suppressed nothing.
+// A range is only reported if every analyzer it names was run, since a
+// directive for an analyzer outside this run may well be in use elsewhere.
+func unusedRanges(ranges []ignoreRange, ran map[string]struct{}) (unused []ignoreRange) {
+	for _, r := range ranges {
+		if !r.pos.IsValid() || r.used {
+			continue
+		}
+		if !allRan(r.selectors, ran) || !canMatch(r.selectors, ran) {
+			continue // Reported by unknownNames, if at all.
+		}
+		unused = append(unused, r)
+	}
+	return
+}
+
+// allRan reports whether every analyzer included by selectors was run. A
+// glob pattern counts as run if it matches any analyzer that was.
+func allRan(selectors []selector, ran map[string]struct{}) bool {
+	for _, s := range selectors {
+		if !s.exclude && !s.matchesAny(ran) {
+			return false
+		}
+	}
+	return true
+}
+
+// ignoreRanges returns the ranges of the package's //ignore directives and
+// rules that are in effect, along with diagnostics for malformed and
+// expired directives.
+func (c Config) ignoreRanges(pass *analysis.Pass, rules []fileRule) (ranges []ignoreRange, problems []analysis.Diagnostic) {
 	for _, file := range pass.Files {
-		ranges = append(ranges, fileIgnores(file, pass)...)
+		fr, fp := c.fileIgnores(file, pass)
+		ranges = append(ranges, fr...)
+		problems = append(problems, fp...)
 	}
+	ranges = append(ranges, ruleRanges(pass, rules)...)
+	now := c.now()
+	ranges = slices.DeleteFunc(ranges, func(r ignoreRange) bool {
+		if r.until.IsZero() || !expired(r.until, now) {
+			return false
+		}
+		problems = append(problems, analysis.Diagnostic{
+			Pos:      r.pos,
+			Category: name,
+			Message: fmt.Sprintf("%s directive expired on %s",
+				r.kind, r.until.Format(time.DateOnly),
+			),
+		})
+		return true
+	})
 	slices.SortFunc(ranges, func(a, b ignoreRange) int {
 		return int(a.start - b.start)
 	})
 	return
 }
 
-func fileIgnores(file *ast.File, pass *analysis.Pass) (ranges []ignoreRange) {
+// expired reports whether the day of until has passed at now, in the
+// location of now.
+func expired(until, now time.Time) bool {
+	y, m, d := until.Date()
+	return !now.Before(time.Date(y, m, d+1, 0, 0, 0, 0, now.Location()))
+}
+
+func (c Config) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (c Config) fileIgnores(file *ast.File, pass *analysis.Pass) (ranges []ignoreRange, problems []analysis.Diagnostic) {
 	if ast.IsGenerated(file) {
 		return []ignoreRange{{
-			start:     file.FileStart,
-			end:       file.End(),
-			analyzers: map[string]struct{}{"all": {}},
-		}}
+			start: file.FileStart,
+			end:   file.End(),
+			directive: directive{
+				selectors: []selector{{analyzer: "all"}},
+			},
+		}}, nil
 	}
-	cmap := ast.NewCommentMap(pass.Fset, file, file.Comments)
+	type region struct {
+		comment *ast.Comment
+		directive
+	}
+	var (
+		cmap    = ast.NewCommentMap(pass.Fset, file, file.Comments)
+		regions []region // Open //ignore:begin directives.
+		problem = func(pos token.Pos, format string, args ...any) {
+			problems = append(problems, analysis.Diagnostic{
+				Pos:      pos,
+				Category: name,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+	)
 	for _, cg := range file.Comments {
-		for _, c := range cg.List {
-			analyzers, in := parseIgnore(c.Text)
-			if analyzers == nil {
+		for _, comment := range cg.List {
+			d, ok := c.parseDirective(comment.Text)
+			if !ok {
+				continue
+			}
+			if d.err != nil {
+				problem(comment.Pos(), "invalid %s directive: %v", d.kind, d.err)
 				continue
 			}
-			if in {
-				ranges = append(ranges, ignoreCommentLine(c, pass, analyzers))
+			d.selectors = c.expandGroups(d.selectors)
+			if d.pkg {
+				if d.in || comment.Pos() >= file.Package {
+					problem(comment.Pos(),
+						"%s directive must precede the package clause",
+						d.kind,
+					)
+					continue
+				}
+				ranges = append(ranges, ignoreRange{
+					start:     file.FileStart,
+					end:       file.End(),
+					directive: d,
+					pos:       comment.Pos(),
+				})
+				continue
+			}
+			if d.begin {
+				regions = append(regions, region{comment, d})
+				continue
+			}
+			if d.end {
+				if len(regions) == 0 {
+					problem(comment.Pos(),
+						"//ignore:end without matching //ignore:begin",
+					)
+					continue
+				}
+				open := regions[len(regions)-1]
+				regions = regions[:len(regions)-1]
+				if d.selectors != nil &&
+					!sameSelectors(d.selectors, open.selectors) {
+					problem(comment.Pos(),
+						"//ignore:end does not match //ignore:begin on line %d",
+						pass.Fset.Position(open.comment.Pos()).Line,
+					)
+					continue
+				}
+				ranges = append(ranges, ignoreRange{
+					start:     lineStart(pass, open.comment.Pos()),
+					end:       lineEnd(pass, comment.Pos()),
+					directive: open.directive,
+					pos:       open.comment.Pos(),
+				})
+				continue
+			}
+			var r ignoreRange
+			if d.in {
+				r = ignoreCommentLine(comment, pass, d)
 			} else {
-				ranges = append(
-					ranges,
-					newIgnoreRange(c, analyzers, file, cmap, cg, pass),
-				)
+				r = newIgnoreRange(comment, d, file, cmap, cg, pass)
 			}
+			r.pos = comment.Pos()
+			ranges = append(ranges, r)
+		}
+	}
+	for _, open := range regions {
+		problem(open.comment.Pos(),
+			"//ignore:begin without matching //ignore:end",
+		)
+	}
+	return
+}
+
+// expandGroups replaces selectors that name a group in c.Groups with a
+// selector for each of the group's members, recursively.
+func (c Config) expandGroups(selectors []selector) (expanded []selector) {
+	for _, s := range selectors {
+		members, ok := c.Groups[s.analyzer]
+		if !ok {
+			expanded = append(expanded, s)
+			continue
+		}
+		group := make([]selector, len(members))
+		for i, m := range members {
+			group[i] = s
+			group[i].analyzer = m
 		}
+		expanded = append(expanded, c.expandGroups(group)...)
 	}
 	return
 }
 
-func newIgnoreRange(comment *ast.Comment, analyzers map[string]struct{}, file *ast.File, cmap ast.CommentMap, group *ast.CommentGroup, pass *analysis.Pass) ignoreRange {
-	if comment.Pos() < file.Package {
+func newIgnoreRange(comment *ast.Comment, d directive, file *ast.File, cmap ast.CommentMap, group *ast.CommentGroup, pass *analysis.Pass) ignoreRange {
+	if d.file || comment.Pos() < file.Package {
 		// Ignore analyzers on this entire file.
-		return ignoreRange{file.FileStart, file.End(), analyzers}
+		return ignoreRange{start: file.FileStart, end: file.End(),
+			directive: d}
 	}
 	node := findCommentNode(comment, cmap)
 	if node != nil && group != nil {
 		if isInlineComment(comment, pass) {
 			// Ignore this comment's associated line.
-			return ignoreCommentLine(comment, pass, analyzers)
+			return ignoreCommentLine(comment, pass, d)
 		} else {
 			// Ignore analyzers on this comment group and its associated node.
 			return ignoreRange{
-				lineStart(pass, min(group.Pos(), node.Pos())),
-				lineEnd(pass, max(group.End(), node.End())),
-				analyzers,
+				start:     lineStart(pass, min(group.Pos(), node.Pos())),
+				end:       lineEnd(pass, max(group.End(), node.End())),
+				directive: d,
 			}
 		}
 	} else if node != nil {
 		// Ignore analyzers on this node.
 		return ignoreRange{
-			lineStart(pass, node.Pos()),
-			lineEnd(pass, node.End()),
-			analyzers,
+			start:     lineStart(pass, node.Pos()),
+			end:       lineEnd(pass, node.End()),
+			directive: d,
 		}
 	} else if group != nil {
 		// Ignore analyzers on this comment group.
 		return ignoreRange{
-			lineStart(pass, group.Pos()),
-			lineEnd(pass, group.End()),
-			analyzers,
+			start:     lineStart(pass, group.Pos()),
+			end:       lineEnd(pass, group.End()),
+			directive: d,
 		}
 	} else {
 		// Ignore analyzers on the current line.
-		return ignoreCommentLine(comment, pass, analyzers)
+		return ignoreCommentLine(comment, pass, d)
 	}
 }
 
@@ -307,12 +620,13 @@ func isInlineComment(comment *ast.Comment, pass *analysis.Pass) bool {
 	return !commentRe.Match(buf[pos:end])
 }
 
-func ignoreCommentLine(comment *ast.Comment, pass *analysis.Pass, analyzers map[string]struct{}) ignoreRange {
+func ignoreCommentLine(comment *ast.Comment, pass *analysis.Pass, d directive) ignoreRange {
 	start, end := lineStart(pass, comment.Pos()), lineEnd(pass, comment.Pos())
 	if start == end {
-		return ignoreRange{comment.Pos(), comment.End(), analyzers}
+		return ignoreRange{start: comment.Pos(), end: comment.End(),
+			directive: d}
 	}
-	return ignoreRange{start, end, analyzers}
+	return ignoreRange{start: start, end: end, directive: d}
 }
 
 // lineStart widens p to the start of its line, since a diagnostic may
@@ -350,71 +664,308 @@ func findCommentNode(comment *ast.Comment, cmap ast.CommentMap) ast.Node {
 	return nil
 }
 
-func filter(diags []analysis.Diagnostic, ranges []ignoreRange, analyzerName string, fset *token.FileSet) func(func(analysis.Diagnostic) bool) {
-	return func(yield func(analysis.Diagnostic) bool) {
-		for _, d := range diags {
-			if !ignoreDiagnostic(&d, ranges, analyzerName, fset) {
-				if !yield(d) {
-					return
-				}
+// ignoreDiagnostic returns how diag is suppressed by ranges, if it is.
+func ignoreDiagnostic(diag *analysis.Diagnostic, ranges []ignoreRange, analyzerName string, fset *token.FileSet) (by []suppression) {
+	if !diag.Pos.IsValid() {
+		return nil
+	}
+	diagPos := fset.Position(diag.Pos)
+
+	// Every matching range is marked as used, not just the first, so that
+	// overlapping directives are not reported as unused.
+	for i := range ranges {
+		r := &ranges[i]
+		if !r.start.IsValid() {
+			continue
+		}
+		if !r.pkg && fset.Position(r.start).Filename != diagPos.Filename {
+			continue
+		}
+		if r.matches(analyzerName, diag) {
+			if r.pkg || diag.Pos >= r.start && diag.Pos <= r.end {
+				r.used = true
+				by = append(by, suppression{r.kind, r.reason})
 			}
 		}
 	}
+	return by
 }
 
-func ignoreDiagnostic(diag *analysis.Diagnostic, ranges []ignoreRange, analyzerName string, fset *token.FileSet) bool {
+// unchangedDiagnostic reports whether diag falls on a line that was not
+// changed. Diagnostics without a position are never unchanged.
+func unchangedDiagnostic(diag *analysis.Diagnostic, changed changedLines, fset *token.FileSet) bool {
 	if !diag.Pos.IsValid() {
 		return false
 	}
-	diagPos := fset.Position(diag.Pos)
+	posn := fset.Position(diag.Pos)
+	return !changed.contains(posn.Filename, posn.Line)
+}
 
-	for _, r := range ranges {
-		if !r.start.IsValid() {
+// matches reports whether the directive applies to a diagnostic reported
+// by the named analyzer: some selector must include it, and none may
+// exclude it.
+func (d directive) matches(analyzerName string, diag *analysis.Diagnostic) bool {
+	var included bool
+	for _, s := range d.selectors {
+		if !s.matches(analyzerName, diag) {
 			continue
 		}
-		if fset.Position(r.start).Filename != diagPos.Filename {
-			continue
+		if s.exclude {
+			return false
 		}
-		if analyzersContains(r.analyzers, analyzerName) {
-			if diag.Pos >= r.start && diag.Pos <= r.end {
-				return true
-			}
+		included = true
+	}
+	return included
+}
+
+func (s selector) matches(analyzerName string, diag *analysis.Diagnostic) bool {
+	if !s.matchesName(analyzerName) {
+		return false
+	}
+	if s.category != "" && s.category != diag.Category {
+		return false
+	}
+	return s.message == nil || s.message.MatchString(diag.Message)
+}
+
+// matchesAny reports whether the selector applies to any of the named
+// analyzers.
+func (s selector) matchesAny(analyzerNames map[string]struct{}) bool {
+	for a := range analyzerNames {
+		if s.matchesName(a) {
+			return true
 		}
 	}
 	return false
 }
 
-func analyzersContains(analyzers map[string]struct{}, analyzerName string) bool {
-	if _, ok := analyzers["all"]; ok {
+// matchesName reports whether the selector applies to the named analyzer.
+func (s selector) matchesName(analyzerName string) bool {
+	if s.analyzer == "all" {
 		return true
 	}
-	_, exists := analyzers[analyzerName]
-	return exists
+	ok, _ := path.Match(s.analyzer, analyzerName)
+	return ok
 }
 
-func parseIgnore(text string) (analyzers map[string]struct{}, in bool) {
-	var (
-		re    = regexp.MustCompile(`//ignore(?::([^/\s]+))?`)
-		match = re.FindStringSubmatch(text)
+var (
+	// ignoreRe matches an //ignore directive and its list of analyzers,
+	// each of which may be followed by a quoted message pattern.
+	ignoreRe = regexp.MustCompile(
+		`//ignore(?::((?:[^/\s"]|/"(?:[^"\\]|\\.)*")+))?`,
 	)
-	if match == nil {
-		return
+	// inlineRe matches the remainder of a comment after an in-line
+	// directive, which must end the comment or be followed by an expiry
+	// date, a reason, or another comment. This keeps prose that mentions
+	// //ignore from acting as a directive. Wrapped prose may still break
+	// just after a mention, so an in-line directive that ends the comment
+	// must also name its analyzers, as //ignore:all does. Prose that breaks
+	// after such a name is taken for a directive all the same.
+	inlineRe = regexp.MustCompile(`^(?:\s*$|\s+//|\s+--|\s+until=)`)
+	// trailingRe matches another comment following a directive.
+	trailingRe = regexp.MustCompile(`\s+//`)
+)
+
+// parseDirective parses text as an //ignore directive, or as one of the
+// foreign directives enabled by c.
+func (c Config) parseDirective(text string) (directive, bool) {
+	if d, ok := parseIgnore(text); ok {
+		return d, true
 	}
-	in = !strings.HasPrefix(text, match[0])
-	analyzers = make(map[string]struct{})
-	if match[1] == "" {
-		analyzers["all"] = struct{}{}
-		return
+	if c.Nolint {
+		if d, ok := parseNolint(text); ok {
+			return d, true
+		}
+	}
+	if c.LintIgnore {
+		if d, ok := parseLintIgnore(text); ok {
+			return d, true
+		}
 	}
-	analyzerList := match[1]
-	if analyzerList == "all" {
-		analyzers["all"] = struct{}{}
+	return directive{}, false
+}
+
+func parseIgnore(text string) (d directive, ok bool) {
+	loc := ignoreRe.FindStringSubmatchIndex(text)
+	if loc == nil {
 		return
 	}
-	for name := range strings.SplitSeq(analyzerList, ",") {
-		if name = strings.TrimSpace(name); name != "" {
-			analyzers[name] = struct{}{}
+	before, after := text[:loc[0]], text[loc[1]:]
+	d.kind, d.in = "//ignore", loc[0] > 0
+	if d.in {
+		if strings.TrimSpace(strings.TrimPrefix(before, "//")) == "" ||
+			strings.TrimRightFunc(before, unicode.IsSpace) == before ||
+			!inlineRe.MatchString(after) ||
+			loc[2] < 0 && strings.TrimSpace(after) == "" {
+			return directive{}, false
 		}
 	}
+	if loc := trailingRe.FindStringIndex(after); loc != nil {
+		after = after[:loc[0]]
+	}
+	trimmed := strings.TrimLeftFunc(after, unicode.IsSpace)
+	if rest, ok := strings.CutPrefix(trimmed, "until="); ok && trimmed != after {
+		date := rest
+		if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+			date, rest = rest[:i], rest[i:]
+		} else {
+			rest = ""
+		}
+		if d.until, d.err = time.Parse(time.DateOnly, date); d.err != nil {
+			d.err = fmt.Errorf("bad until date %q: want YYYY-MM-DD", date)
+		}
+		after, trimmed = rest, strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+	if rest, ok := strings.CutPrefix(trimmed, "--"); ok && trimmed != after {
+		d.reason = strings.TrimSpace(rest)
+	}
+	var list string
+	if loc[2] >= 0 {
+		list = text[loc[2]:loc[3]]
+	}
+	// Regions are opened with //ignore:begin[:names] and closed with
+	// //ignore:end[:names]. Package-wide directives are spelled
+	// //ignore:package[:names].
+	if head, names, _ := strings.Cut(list, ":"); head == "package" {
+		d.kind, d.pkg, list = "//ignore:package", true, names
+	} else if head == "begin" {
+		d.begin, list = true, names
+	} else if head == "end" {
+		d.end, list = true, names
+		if list == "" {
+			return d, true // Closes whichever region is open.
+		}
+	}
+	var err error
+	d.selectors, err = parseAnalyzers(list)
+	d.err = errors.Join(d.err, err)
+	return d, true
+}
+
+// parseAnalyzers parses a comma-separated list of analyzer names. An empty
+// list means all analyzers.
+//
+// A name may be followed by a dot and a diagnostic category, as in
+// mychecker.SA1019, and then by a slash and a quoted regular expression, as
+// in errcheck/"\.Close", to match only some of the analyzer's diagnostics.
+// A name may also be a glob pattern, as in line*, and a leading minus
+// excludes what would otherwise be matched, as in all,-errcheck. gofmt only
+// leaves a directive in a doc comment alone if it starts with a letter or
+// digit, so lists there should not start with a pattern or exclusion.
+func parseAnalyzers(list string) (selectors []selector, err error) {
+	if list == "" || list == "all" {
+		return []selector{{analyzer: "all"}}, nil
+	}
+	for _, item := range splitList(list) {
+		item, pattern, hasPattern := strings.Cut(item, `/"`)
+		name, category, _ := strings.Cut(strings.TrimSpace(item), ".")
+		name, exclude := strings.CutPrefix(name, "-")
+		if name == "" {
+			continue
+		}
+		if _, err := path.Match(name, ""); err != nil {
+			return nil, fmt.Errorf("bad analyzer pattern %q: %w",
+				name, err,
+			)
+		}
+		sel := selector{analyzer: name, category: category, exclude: exclude}
+		if hasPattern {
+			pattern = strings.TrimSuffix(pattern, `"`)
+			if sel.message, err = regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("bad message pattern for %s: %w",
+					item, err,
+				)
+			}
+		}
+		selectors = append(selectors, sel)
+	}
 	return
 }
+
+// String returns s in the syntax parseAnalyzers accepts.
+func (s selector) String() string {
+	var b strings.Builder
+	if s.exclude {
+		b.WriteString("-")
+	}
+	b.WriteString(s.analyzer)
+	if s.category != "" {
+		b.WriteString("." + s.category)
+	}
+	if s.message != nil {
+		b.WriteString(`/"` + s.message.String() + `"`)
+	}
+	return b.String()
+}
+
+// sameSelectors reports whether a and b select the same diagnostics as
+// written, ignoring order and repetition.
+func sameSelectors(a, b []selector) bool {
+	set := func(sels []selector) (s []string) {
+		for _, sel := range sels {
+			s = append(s, sel.String())
+		}
+		slices.Sort(s)
+		return slices.Compact(s)
+	}
+	return slices.Equal(set(a), set(b))
+}
+
+// splitList splits list at commas that are not within quotes.
+func splitList(list string) (items []string) {
+	var quoted, escaped bool
+	start := 0
+	for i, r := range list {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			items = append(items, list[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, list[start:])
+}
+
+var (
+	nolintRe     = regexp.MustCompile(`^//nolint(?::([^\s/]+))?(?:\s|$)`)
+	lintIgnoreRe = regexp.MustCompile(
+		`^//lint:(ignore|file-ignore)\s+([^\s/]+)(?:\s+(.*))?$`,
+	)
+)
+
+// parseNolint parses a golangci-lint directive, such as:
+//
+//	//nolint:errcheck,linelen // reason
+func parseNolint(text string) (d directive, ok bool) {
+	m := nolintRe.FindStringSubmatch(text)
+	if m == nil {
+		return
+	}
+	d.kind = "//nolint"
+	d.selectors, d.err = parseAnalyzers(m[1])
+	rest := strings.TrimSpace(text[len(m[0]):])
+	if reason, ok := strings.CutPrefix(rest, "//"); ok {
+		d.reason = strings.TrimSpace(reason)
+	}
+	return d, true
+}
+
+// parseLintIgnore parses a staticcheck directive, such as:
+//
+//	//lint:ignore SA1019 reason
+//	//lint:file-ignore SA1019 reason
+func parseLintIgnore(text string) (d directive, ok bool) {
+	m := lintIgnoreRe.FindStringSubmatch(text)
+	if m == nil {
+		return
+	}
+	d.kind, d.file = "//lint:"+m[1], m[1] == "file-ignore"
+	d.selectors, d.err = parseAnalyzers(m[2])
+	d.reason = strings.TrimSpace(m[3])
+	return d, true
+}
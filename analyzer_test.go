@@ -1,17 +1,27 @@
 package checker
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/packages"
 )
 
 type identCheck func(pass *analysis.Pass, ident *ast.Ident)
@@ -146,6 +156,98 @@ var (
 	}
 )
 
+// declKinds reports every top-level declaration under a category naming its
+// kind.
+var declKinds = &analysis.Analyzer{
+	Name: "declkinds",
+	Doc:  "reports top-level declarations by kind",
+	Run: func(pass *analysis.Pass) (any, error) {
+		for _, file := range pass.Files {
+			for _, decl := range file.Decls {
+				switch decl := decl.(type) {
+				case *ast.FuncDecl:
+					pass.Report(analysis.Diagnostic{
+						Pos:      decl.Name.Pos(),
+						Category: "func",
+						Message:  decl.Name.Name + " is a func",
+					})
+				case *ast.GenDecl:
+					for _, spec := range decl.Specs {
+						vspec, ok := spec.(*ast.ValueSpec)
+						if !ok {
+							continue
+						}
+						for _, name := range vspec.Names {
+							pass.Report(analysis.Diagnostic{
+								Pos:      name.Pos(),
+								Category: decl.Tok.String(),
+								Message:  name.Name + " is a " + decl.Tok.String(),
+							})
+						}
+					}
+				}
+			}
+		}
+		return nil, nil
+	},
+}
+
+// lowerNames reports exported functions, suggesting that their declarations
+// be renamed to unexported names. Uses of the functions are not renamed.
+var lowerNames = &analysis.Analyzer{
+	Name: "lowernames",
+	Doc:  "reports exported functions and suggests unexporting them",
+	Run: func(pass *analysis.Pass) (any, error) {
+		for _, file := range pass.Files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || !fn.Name.IsExported() {
+					continue
+				}
+				lower := strings.ToLower(fn.Name.Name[:1]) + fn.Name.Name[1:]
+				pass.Report(analysis.Diagnostic{
+					Pos:     fn.Name.Pos(),
+					Message: fn.Name.Name + " is exported",
+					SuggestedFixes: []analysis.SuggestedFix{{
+						Message: "rename to " + lower,
+						TextEdits: []analysis.TextEdit{{
+							Pos:     fn.Name.Pos(),
+							End:     fn.Name.End(),
+							NewText: []byte(lower),
+						}},
+					}},
+				})
+			}
+		}
+		return nil, nil
+	},
+}
+
+// trimDigits reports names that end in a digit, suggesting that the last
+// digit be dropped, so that a name ending in several digits takes a round of
+// fixes for each.
+var trimDigits = &analysis.Analyzer{
+	Name: "trimdigits",
+	Doc:  "reports names ending in a digit and suggests dropping it",
+	Run: identAnalyze(func(pass *analysis.Pass, ident *ast.Ident) {
+		last := ident.Name[len(ident.Name)-1]
+		if last < '0' || last > '9' {
+			return
+		}
+		pass.Report(analysis.Diagnostic{
+			Pos:     ident.Pos(),
+			Message: ident.Name + " ends in a digit",
+			SuggestedFixes: []analysis.SuggestedFix{{
+				Message: "rename to " + ident.Name[:len(ident.Name)-1],
+				TextEdits: []analysis.TextEdit{{
+					Pos: ident.End() - 1,
+					End: ident.End(),
+				}},
+			}},
+		})
+	}),
+}
+
 // dependentAnalyzer depends on publicNames and uses its results
 var dependentAnalyzer = &analysis.Analyzer{
 	Name:     "dependent",
@@ -218,57 +320,459 @@ func TestFileLevelNolint(t *testing.T) {
 	)
 }
 
+func TestUnusedNolint(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(),
+		NewAnalyzer(publicNames, numberedNames), "unused",
+	)
+}
+
 func TestParseNolint(t *testing.T) {
+	var (
+		all    = []selector{{analyzer: "all"}}
+		test1  = []selector{{analyzer: "test1"}}
+		test12 = []selector{{analyzer: "test1"}, {analyzer: "test2"}}
+	)
 	tests := []struct {
 		input         string
-		wantAnalyzers map[string]struct{}
+		wantSelectors []selector
 		wantIn        bool
-	}{{
-		"//ignore", map[string]struct{}{"all": {}}, false,
-	}, {
-		"//ignore:all", map[string]struct{}{"all": {}}, false,
-	}, {
-		"//ignore:test1", map[string]struct{}{"test1": {}}, false,
-	}, {"//ignore:test1,test2", map[string]struct{}{
-		"test1": {}, "test2": {},
-	}, false}, {"//ignore:all // This is a comment",
-		map[string]struct{}{
-			"all": {},
-		}, false}, {"//ignore:test1 // Explanatory comment",
-		map[string]struct{}{
-			"test1": {},
-		}, false}, {"//ignore:test1,test2 // Multiple analyzers",
-		map[string]struct{}{
-			"test1": {}, "test2": {},
-		}, false}, {
-		"// not ignore", nil, false,
-	}, {"//other", nil, false}, {"// This is a comment. //ignore:all",
-		map[string]struct{}{
-			"all": {},
-		}, true},
+	}{
+		{"//ignore", all, false},
+		{"//ignore:all", all, false},
+		{"//ignore:test1", test1, false},
+		{"//ignore:test1,test2", test12, false},
+		{"//ignore:all // This is a comment", all, false},
+		{"//ignore:test1 // Explanatory comment", test1, false},
+		{"//ignore:test1,test2 // Multiple analyzers", test12, false},
+		{"// not ignore", nil, false},
+		{"//other", nil, false},
+		{"// This is a comment. //ignore:all", all, true},
 		{"// This is a comment. //ignore:all // And another comment.",
-			map[string]struct{}{"all": {}}, true}}
+			all, true},
+		{"// Prose about //ignore directives.", nil, false},
+		{"// Prose about //ignore:all directives.", nil, false},
+		{"//\t//ignore:all", nil, false},
+		{"// Prose.//ignore:all", nil, false},
+		{"// This is the form in which //ignore", nil, false},
+		{"// Legacy. //ignore -- kept for callers", all, true},
+	}
 
 	for _, tt := range tests {
-		gotAnalyzers, gotIn := parseIgnore(tt.input)
-		if tt.wantAnalyzers == nil && gotAnalyzers != nil {
-			t.Errorf("parseNolint(%q) = %v, want nil", tt.input, gotAnalyzers)
-		} else if gotAnalyzers == nil && tt.wantAnalyzers != nil {
-			t.Errorf("parseNolint(%q) = nil, want %v",
-				tt.input, tt.wantAnalyzers,
+		got, ok := parseIgnore(tt.input)
+		if ok != (tt.wantSelectors != nil) {
+			t.Errorf("parseNolint(%q) ok = %t, want %t",
+				tt.input, ok, tt.wantSelectors != nil,
 			)
-		} else if !cmp.Equal(gotAnalyzers, tt.wantAnalyzers) {
-			t.Errorf("analyzers -want +got\n%s",
-				cmp.Diff(gotAnalyzers, tt.wantAnalyzers),
+		} else if !cmp.Equal(got.selectors, tt.wantSelectors,
+			cmp.AllowUnexported(selector{}),
+		) {
+			t.Errorf("parseNolint(%q) selectors -want +got\n%s", tt.input,
+				cmp.Diff(tt.wantSelectors, got.selectors,
+					cmp.AllowUnexported(selector{}),
+				),
 			)
-		} else if gotIn != tt.wantIn {
+		} else if got.in != tt.wantIn {
 			t.Errorf("parseNoLint(%q) in = %t, want %t",
-				tt.input, gotIn, tt.wantIn,
+				tt.input, got.in, tt.wantIn,
+			)
+		}
+	}
+}
+
+func TestParseIgnoreReason(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantReason string
+	}{
+		{"//ignore:errcheck", ""},
+		{"//ignore:errcheck -- close error is irrelevant",
+			"close error is irrelevant"},
+		{"//ignore -- generated by hand", "generated by hand"},
+		{"//ignore:errcheck --", ""},
+		{"//ignore:errcheck -- see https://go.dev // want",
+			"see https://go.dev"},
+		{"//ignore:errcheck // -- not a reason", ""},
+		{"// Comment. //ignore:errcheck -- reason", "reason"},
+	}
+	for _, tt := range tests {
+		got, ok := parseIgnore(tt.input)
+		if !ok {
+			t.Errorf("parseIgnore(%q) ok = false, want true", tt.input)
+		} else if got.reason != tt.wantReason {
+			t.Errorf("parseIgnore(%q).reason = %q, want %q",
+				tt.input, got.reason, tt.wantReason,
+			)
+		}
+	}
+}
+
+func TestRequireReason(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(),
+		Config{RequireReason: true}.NewAnalyzer(publicNames), "reason",
+	)
+}
+
+func TestParseIgnoreUntil(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantUntil  time.Time
+		wantReason string
+		wantErr    bool
+	}{
+		{"//ignore:nilness", time.Time{}, "", false},
+		{"//ignore:nilness until=2026-12-31",
+			time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC), "", false},
+		{"//ignore:nilness until=2026-12-31 -- flaky upstream",
+			time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+			"flaky upstream", false},
+		{"// Comment. //ignore:nilness until=2026-12-31",
+			time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC), "", false},
+		{"//ignore:nilness until=12/31/2026", time.Time{}, "", true},
+		{"//ignore:nilness until=", time.Time{}, "", true},
+	}
+	for _, tt := range tests {
+		got, ok := parseIgnore(tt.input)
+		if !ok {
+			t.Errorf("parseIgnore(%q) ok = false, want true", tt.input)
+			continue
+		}
+		if (got.err != nil) != tt.wantErr {
+			t.Errorf("parseIgnore(%q).err = %v, want error: %t",
+				tt.input, got.err, tt.wantErr,
+			)
+		}
+		if !got.until.Equal(tt.wantUntil) {
+			t.Errorf("parseIgnore(%q).until = %v, want %v",
+				tt.input, got.until, tt.wantUntil,
+			)
+		}
+		if got.reason != tt.wantReason {
+			t.Errorf("parseIgnore(%q).reason = %q, want %q",
+				tt.input, got.reason, tt.wantReason,
+			)
+		}
+	}
+}
+
+func TestIgnoreUntil(t *testing.T) {
+	pst := time.FixedZone("PST", -8*60*60)
+	for _, now := range []time.Time{
+		time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC),
+		// Already June 16 in UTC, but June 15 has not passed locally.
+		time.Date(2026, 6, 15, 20, 0, 0, 0, pst),
+	} {
+		t.Run(now.String(), func(t *testing.T) {
+			analysistest.Run(t, analysistest.TestData(),
+				Config{Now: func() time.Time { return now }}.
+					NewAnalyzer(publicNames),
+				"until",
+			)
+		})
+	}
+}
+
+func TestNolintDialect(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(),
+		Config{Nolint: true}.NewAnalyzer(publicNames, numberedNames),
+		"nolint",
+	)
+}
+
+func TestLintIgnoreDialect(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(),
+		Config{LintIgnore: true}.NewAnalyzer(publicNames, numberedNames),
+		"lintignore", "lintfileignore",
+	)
+}
+
+func TestParseDialects(t *testing.T) {
+	all := []selector{{analyzer: "all"}}
+	sa1019 := []selector{{analyzer: "SA1019"}}
+	tests := []struct {
+		input         string
+		wantKind      string
+		wantSelectors []selector
+		wantReason    string
+		wantFile      bool
+	}{
+		{"//nolint", "//nolint", all, "", false},
+		{"//nolint:all", "//nolint", all, "", false},
+		{"//nolint:errcheck,linelen // legacy", "//nolint", []selector{
+			{analyzer: "errcheck"}, {analyzer: "linelen"},
+		}, "legacy", false},
+		{"// nolint:errcheck", "", nil, "", false},
+		{"//nolintfoo", "", nil, "", false},
+		{"//lint:ignore SA1019 still supported", "//lint:ignore",
+			sa1019, "still supported", false},
+		{"//lint:file-ignore SA1019,SA4006 vendored", "//lint:file-ignore",
+			[]selector{{analyzer: "SA1019"}, {analyzer: "SA4006"}},
+			"vendored", true},
+		{"//lint:ignore SA1019", "//lint:ignore", sa1019, "", false},
+		{"//lint:ignore", "", nil, "", false},
+	}
+	c := Config{Nolint: true, LintIgnore: true}
+	for _, tt := range tests {
+		got, ok := c.parseDirective(tt.input)
+		if ok != (tt.wantSelectors != nil) {
+			t.Errorf("parseDirective(%q) ok = %t, want %t",
+				tt.input, ok, tt.wantSelectors != nil,
+			)
+			continue
+		}
+		if got.kind != tt.wantKind {
+			t.Errorf("parseDirective(%q).kind = %q, want %q",
+				tt.input, got.kind, tt.wantKind,
+			)
+		}
+		if !cmp.Equal(got.selectors, tt.wantSelectors,
+			cmp.AllowUnexported(selector{}),
+		) {
+			t.Errorf("parseDirective(%q).selectors -want +got\n%s",
+				tt.input, cmp.Diff(tt.wantSelectors, got.selectors,
+					cmp.AllowUnexported(selector{}),
+				),
+			)
+		}
+		if got.reason != tt.wantReason {
+			t.Errorf("parseDirective(%q).reason = %q, want %q",
+				tt.input, got.reason, tt.wantReason,
+			)
+		}
+		if got.file != tt.wantFile {
+			t.Errorf("parseDirective(%q).file = %t, want %t",
+				tt.input, got.file, tt.wantFile,
+			)
+		}
+	}
+}
+
+func TestRegionNolint(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(),
+		NewAnalyzer(publicNames, numberedNames), "region",
+	)
+}
+
+func TestPackageLevelNolint(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(),
+		NewAnalyzer(publicNames, numberedNames), "pkglevel",
+	)
+}
+
+func TestUnknownNolint(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(),
+		Config{Nolint: true}.NewAnalyzer(dependentAnalyzer), "unknown",
+	)
+}
+
+func TestKnownAnalyzers(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(),
+		Config{KnownAnalyzers: []string{"linelen"}}.NewAnalyzer(publicNames),
+		"otherrun",
+	)
+}
+
+func TestClosest(t *testing.T) {
+	known := map[string]struct{}{
+		"errcheck": {}, "linelen": {}, "nilness": {}, "tidytypes": {},
+	}
+	tests := []struct{ name, want string }{
+		{"errchek", "errcheck"},
+		{"errcheck", "errcheck"},
+		{"linlen", "linelen"},
+		{"nilnes", "nilness"},
+		{"tidy", ""},
+		{"x", ""},
+		{"bogus", ""},
+	}
+	for _, tt := range tests {
+		if got := closest(tt.name, known); got != tt.want {
+			t.Errorf("closest(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMessageNolint(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(),
+		NewAnalyzer(publicNames, numberedNames), "message",
+	)
+}
+
+func TestParseIgnoreMessages(t *testing.T) {
+	tests := []struct {
+		input string
+		want  map[string]string
+	}{
+		{"//ignore:errcheck", nil},
+		{`//ignore:errcheck/"\.Close"`, map[string]string{
+			"errcheck": `\.Close`,
+		}},
+		{`//ignore:errcheck/"a, b",linelen`, map[string]string{
+			"errcheck": "a, b",
+		}},
+		{`//ignore:errcheck/"a // b" -- reason`, map[string]string{
+			"errcheck": "a // b",
+		}},
+		{`//ignore:errcheck/"\"",all/"x"`, map[string]string{
+			"errcheck": `\"`, "all": "x",
+		}},
+	}
+	for _, tt := range tests {
+		d, ok := parseIgnore(tt.input)
+		if !ok || d.err != nil {
+			t.Errorf("parseIgnore(%q) = %v, %t, want ok", tt.input, d.err, ok)
+			continue
+		}
+		var got map[string]string
+		for _, sel := range d.selectors {
+			if sel.message == nil {
+				continue
+			}
+			if got == nil {
+				got = make(map[string]string)
+			}
+			got[sel.analyzer] = sel.message.String()
+		}
+		if !cmp.Equal(got, tt.want) {
+			t.Errorf("parseIgnore(%q).messages -want +got\n%s",
+				tt.input, cmp.Diff(tt.want, got),
+			)
+		}
+	}
+}
+
+func TestParseIgnoreCategories(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []selector
+	}{
+		{"//ignore", []selector{{analyzer: "all"}}},
+		{"//ignore:mychecker.SA1019", []selector{
+			{analyzer: "mychecker", category: "SA1019"},
+		}},
+		{"//ignore:mychecker.SA1019,mychecker.SA4006,errcheck", []selector{
+			{analyzer: "mychecker", category: "SA1019"},
+			{analyzer: "mychecker", category: "SA4006"},
+			{analyzer: "errcheck"},
+		}},
+		{"//ignore:all.deprecated", []selector{
+			{analyzer: "all", category: "deprecated"},
+		}},
+		{"//ignore:all,-errcheck", []selector{
+			{analyzer: "all"},
+			{analyzer: "errcheck", exclude: true},
+		}},
+		{"//ignore:line*,-mychecker.SA1019", []selector{
+			{analyzer: "line*"},
+			{analyzer: "mychecker", category: "SA1019", exclude: true},
+		}},
+	}
+	for _, tt := range tests {
+		d, ok := parseIgnore(tt.input)
+		if !ok || d.err != nil {
+			t.Errorf("parseIgnore(%q) = %v, %t, want ok", tt.input, d.err, ok)
+			continue
+		}
+		if !cmp.Equal(d.selectors, tt.want, cmp.AllowUnexported(selector{})) {
+			t.Errorf("parseIgnore(%q).selectors -want +got\n%s", tt.input,
+				cmp.Diff(tt.want, d.selectors, cmp.AllowUnexported(selector{})),
+			)
+		}
+	}
+}
+
+func TestGlobNolint(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(),
+		NewAnalyzer(publicNames, numberedNames), "glob",
+	)
+}
+
+func TestGroupNolint(t *testing.T) {
+	c := Config{Groups: map[string][]string{
+		"names":  {"publicnames", "numberednames"},
+		"naming": {"names"},
+	}}
+	analysistest.Run(t, analysistest.TestData(),
+		c.NewAnalyzer(publicNames, numberedNames), "group",
+	)
+}
+
+func TestGroupCycleDetection(t *testing.T) {
+	err := detectGroupCycles(map[string][]string{
+		"a": {"b", "errcheck"},
+		"b": {"c"},
+		"c": {"a"},
+	})
+	if err == nil {
+		t.Fatal("Expected cycle detection error, but got none")
+	}
+	if !strings.Contains(err.Error(), "circular group definition") {
+		t.Errorf("Expected circular group definition error, got: %v", err)
+	}
+	err = detectGroupCycles(map[string][]string{
+		"a": {"b", "c"},
+		"b": {"c"},
+		"c": {"errcheck"},
+	})
+	if err != nil {
+		t.Errorf("detectGroupCycles(acyclic) = %v, want nil", err)
+	}
+}
+
+func TestRules(t *testing.T) {
+	dir := filepath.Join(analysistest.TestData(), "rules")
+	rules, err := loadRules(dir)
+	if err != nil {
+		t.Fatalf("loadRules(%q) = %v", dir, err)
+	}
+	analysistest.Run(t, dir,
+		Config{Ignore: rules}.NewAnalyzer(publicNames, numberedNames),
+		"./...",
+	)
+}
+
+func TestBadRules(t *testing.T) {
+	for _, r := range []Rule{
+		{Path: "[", Analyzer: "errcheck"},
+		{Path: "cmd", Analyzer: ""},
+		{Path: "cmd", Analyzer: "err["},
+		{Path: "cmd", Analyzer: "errcheck", Message: "("},
+	} {
+		if _, err := (Config{Ignore: []Rule{r}}).compileRules(); err == nil {
+			t.Errorf("compileRules(%+v) = nil, want error", r)
+		}
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"", "a/b.go", true},
+		{"a", "a/b.go", true},
+		{"a", "a/b/c.go", true},
+		{"a/b", "a/b/c.go", true},
+		{"b", "a/b/c.go", false},
+		{"*/b", "a/b/c.go", true},
+		{"a/*.go", "a/b.go", true},
+		{"a/*.go", "a/b/c.go", false},
+		{"c.go", "a/b/c.go", false},
+	}
+	for _, tt := range tests {
+		if got := matchPath(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchPath(%q, %q) = %t, want %t",
+				tt.pattern, tt.name, got, tt.want,
 			)
 		}
 	}
 }
 
+func TestCategoryNolint(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(),
+		NewAnalyzer(declKinds), "category",
+	)
+}
+
 func TestBlockLevelNolint(t *testing.T) {
 	analysistest.Run(t, analysistest.TestData(),
 		NewAnalyzer(shortNames, underscoreNames), "blocklevel",
@@ -432,8 +936,1190 @@ func TestConcurrentFactAccess(t *testing.T) {
 		},
 		Report: func(analysis.Diagnostic) {},
 	}
-	_, err := runAnalyzers(pass, []*analysis.Analyzer{writer, reader})
+	_, err := Config{}.runAnalyzers(pass, []*analysis.Analyzer{writer, reader})
 	if err != nil {
 		t.Errorf("runAnalyzers(pass, ...) = %v, want nil", err)
 	}
 }
+
+func TestResultAnalyzers(t *testing.T) {
+	results := analysistest.Run(t, analysistest.TestData(),
+		NewAnalyzer(publicNames, numberedNames), "multiple",
+	)
+	var got []string
+	for _, r := range results {
+		for _, d := range r.Action.Result.(*result).diags {
+			got = append(got, d.analyzer+": "+d.Message)
+		}
+	}
+	slices.Sort(got)
+	want := []string{
+		"numberednames: item2 has numbers",
+		"publicnames: AnotherPublic is public",
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("reported diagnostics (-want +got):\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := fingerprint("errcheck", "a/b.go", "error not checked", "\tf.Close()")
+	if got := fingerprint("errcheck", "a/b.go", "error not checked",
+		"  f.Close()  ",
+	); got != fp {
+		t.Errorf("fingerprint changed with indentation: %s != %s", got, fp)
+	}
+	for _, args := range [][4]string{
+		{"linelen", "a/b.go", "error not checked", "f.Close()"},
+		{"errcheck", "a/c.go", "error not checked", "f.Close()"},
+		{"errcheck", "a/b.go", "other message", "f.Close()"},
+		{"errcheck", "a/b.go", "error not checked", "g.Close()"},
+	} {
+		if got := fingerprint(args[0], args[1], args[2], args[3]); got == fp {
+			t.Errorf("fingerprint%q = %s, same as original", args, got)
+		}
+	}
+}
+
+func TestDiffBaseline(t *testing.T) {
+	e := func(fp string) baselineEntry { return baselineEntry{Fingerprint: fp} }
+	current := []baselineEntry{e("a"), e("b"), e("b"), e("c")}
+	recorded := []baselineEntry{e("a"), e("b"), e("d")}
+	added, fixed := diffBaseline(current, recorded)
+	if want := []int{2, 3}; !cmp.Equal(added, want) {
+		t.Errorf("added = %v, want %v", added, want)
+	}
+	if want := []baselineEntry{e("d")}; !cmp.Equal(fixed, want) {
+		t.Errorf("fixed = %v, want %v", fixed, want)
+	}
+}
+
+func TestBaselineRoundTrip(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "baseline.json")
+	if got, err := readBaseline(name); err != nil || got != nil {
+		t.Fatalf("readBaseline(missing) = %v, %v, want nil, nil", got, err)
+	}
+	entries := []baselineEntry{
+		{Fingerprint: "2", Analyzer: "linelen", File: "b.go", Message: "m"},
+		{Fingerprint: "1", Analyzer: "errcheck", File: "a.go", Message: "m"},
+	}
+	if err := writeBaseline(name, entries); err != nil {
+		t.Fatalf("writeBaseline() = %v", err)
+	}
+	got, err := readBaseline(name)
+	if err != nil {
+		t.Fatalf("readBaseline() = %v", err)
+	}
+	want := []baselineEntry{entries[1], entries[0]}
+	if !cmp.Equal(got, want) {
+		t.Errorf("readBaseline() (-want +got):\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestUnusedInBuilds(t *testing.T) {
+	// The package directive is unused in the package, but used in its test
+	// build, so it is not reported. The other directive is unused in both.
+	tests, ok := runHelper(t, newTestModule(t, "unusedbuilds"),
+		"unusedbuilds", "",
+	)
+	if ok {
+		t.Fatal("unusedbuilds helper passed, want failure")
+	}
+	names, want := sortedKeys(tests), []string{"TestHelperProcess"}
+	if !cmp.Equal(names, want) {
+		t.Errorf("unusedbuilds helper failed %v, want %v", names, want)
+	}
+	got := tests["TestHelperProcess"]
+	if want := "unused //ignore directive"; strings.Count(got, want) != 1 {
+		t.Errorf("helper failed with\n%s\nwant %q once", got, want)
+	}
+	if strings.Contains(got, "//ignore:package") {
+		t.Errorf("helper failed with\n%s\nwant package directive used", got)
+	}
+}
+
+func TestRunSeparate(t *testing.T) {
+	dir := newTestModule(t, "separate")
+	tests, ok := runHelper(t, dir, "separate", "")
+	if ok {
+		t.Fatal("separate helper passed, want failure")
+	}
+	// Only the subtest of the analyzer with errors fails, and it fails once
+	// for each of its diagnostics, beginning with the diagnostic's position.
+	names := sortedKeys(tests)
+	want := []string{"TestHelperProcess", "TestHelperProcess/numberednames"}
+	if !cmp.Equal(names, want) {
+		t.Errorf("separate helper failed %v, want %v", names, want)
+	}
+	var errs []string
+	got := tests["TestHelperProcess/numberednames"]
+	for line := range strings.Lines(got) {
+		if _, msg, ok := strings.Cut(line, ": separate.go:"); ok {
+			errs = append(errs, "separate.go:"+strings.TrimSpace(msg))
+		}
+	}
+	want = []string{
+		"separate.go:3:5: [numberednames] item1 has numbers",
+		"separate.go:3:12: [numberednames] item2 has numbers",
+	}
+	if !cmp.Equal(errs, want) {
+		t.Errorf("numberednames errors (-want +got):\n%s", cmp.Diff(want, errs))
+	}
+	// Warnings are logged, without failing their subtest.
+	out, ok := execHelper(t, dir, "separate", "publicnames", "-test.v")
+	if !ok {
+		t.Errorf("publicnames subtest failed:\n%s", out)
+	}
+	warning := "separate.go:5:6: warning: [publicnames] Public is public"
+	if !strings.Contains(out, warning) {
+		t.Errorf("publicnames subtest logged\n%s\nwant %q", out, warning)
+	}
+}
+
+func TestPackageLevelTests(t *testing.T) {
+	// The directives cover the package, its test build and its external
+	// test package, each of which has some of its files.
+	t.Chdir(newTestModule(t, "pkgtests"))
+	Run(t, publicNames)
+}
+
+func TestBaseline(t *testing.T) {
+	dir := newTestModule(t, "recorded")
+	t.Chdir(dir)
+	Config{Baseline: "baseline.json", UpdateBaseline: true}.Run(t, publicNames)
+	entries, err := readBaseline("baseline.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.File+": "+e.Message)
+	}
+	want := []string{
+		"recorded.go: First is public",
+		"recorded.go: Second is public",
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("recorded baseline (-want +got):\n%s", cmp.Diff(want, got))
+	}
+	// The recorded diagnostics no longer fail the test.
+	Config{Baseline: "baseline.json"}.Run(t, publicNames)
+
+	src := "package recorded\n\nfunc First() {}\n\nfunc Third() {}\n"
+	if err := os.WriteFile("recorded.go", []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests, ok := runHelper(t, dir, "baseline", "")
+	if ok {
+		t.Fatal("baseline helper passed, want failure")
+	}
+	got = nil
+	for _, msg := range []string{
+		"First is public", "Second is public", "Third is public",
+	} {
+		if strings.Contains(tests["TestHelperProcess/publicnames"], msg) {
+			got = append(got, msg)
+		}
+	}
+	if want := []string{"Third is public"}; !cmp.Equal(got, want) {
+		t.Errorf("publicnames reported %v, want %v", got, want)
+	}
+	fixed := "1 baseline entries no longer occur; update baseline.json\n" +
+		"recorded.go: [publicnames] Second is public\n"
+	if got := tests["TestHelperProcess"]; !strings.Contains(got, fixed) {
+		t.Errorf("helper failed with\n%s\nwant %q", got, fixed)
+	}
+}
+
+func TestCompareCounts(t *testing.T) {
+	current := counts{
+		"a": {"errcheck": 3, "linelen": 1},
+		"b": {"errcheck": 2},
+	}
+	budgets := counts{
+		"a": {"errcheck": 3, "linelen": 2},
+		"b": {"errcheck": 1},
+		"c": {"linelen": 4},
+	}
+	over, under := compareCounts(current, budgets)
+	wantOver := []budgetChange{{"b", "errcheck", 2, 1}}
+	wantUnder := []budgetChange{
+		{"a", "linelen", 1, 2},
+		{"c", "linelen", 0, 4},
+	}
+	opt := cmp.AllowUnexported(budgetChange{})
+	if !cmp.Equal(over, wantOver, opt) {
+		t.Errorf("over (-want +got):\n%s", cmp.Diff(wantOver, over, opt))
+	}
+	if !cmp.Equal(under, wantUnder, opt) {
+		t.Errorf("under (-want +got):\n%s", cmp.Diff(wantUnder, under, opt))
+	}
+}
+
+func TestRatchet(t *testing.T) {
+	dir := newTestModule(t, "recorded")
+	t.Chdir(dir)
+	Config{Ratchet: "ratchet.json", UpdateRatchet: true}.Run(t,
+		publicNames, numberedNames,
+	)
+	got, err := readRatchet("ratchet.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := counts{
+		"example.com/recorded": {"publicnames": 2, "numberednames": 1},
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("recorded ratchet (-want +got):\n%s", cmp.Diff(want, got))
+	}
+	// Counts within their budgets do not fail the test.
+	Config{Ratchet: "ratchet.json"}.Run(t, publicNames, numberedNames)
+
+	src := "package recorded\n\nfunc First() {}\n\nfunc Second() {}\n\n" +
+		"func Third() {}\n\nvar item1 int\n"
+	if err := os.WriteFile("recorded.go", []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests, ok := runHelper(t, dir, "ratchet", "")
+	if ok {
+		t.Fatal("ratchet helper passed, want failure")
+	}
+	// Only the analyzer over its budget reports its diagnostics.
+	wantFailed := []string{
+		"TestHelperProcess", "TestHelperProcess/publicnames",
+	}
+	if names := sortedKeys(tests); !cmp.Equal(names, wantFailed) {
+		t.Errorf("ratchet helper failed %v, want %v", names, wantFailed)
+	}
+	for _, msg := range []string{
+		"First is public", "Second is public", "Third is public",
+	} {
+		if !strings.Contains(tests["TestHelperProcess/publicnames"], msg) {
+			t.Errorf("publicnames did not report %q", msg)
+		}
+	}
+	exceeded := "diagnostic counts exceed ratchet.json\n" +
+		"example.com/recorded: publicnames: 3 diagnostics, budget 2\n"
+	if got := tests["TestHelperProcess"]; !strings.Contains(got, exceeded) {
+		t.Errorf("ratchet helper failed with\n%s\nwant %q", got, exceeded)
+	}
+}
+
+func TestParseDiff(t *testing.T) {
+	diff := `diff --git a/a.go b/a.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/a.go
+@@ -3 +3 @@ package a
+-var x = 1
++var x = 2
+@@ -10,0 +11,2 @@ func f() {
++	g()
++	h()
+@@ -20,2 +21,0 @@ func f() {
+-	i()
+-	j()
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+--- a/gone.go
++++ /dev/null
+@@ -1,3 +0,0 @@
+-package a
+`
+	got, err := parseDiff("/root", []byte(diff))
+	if err != nil {
+		t.Fatalf("parseDiff() = %v", err)
+	}
+	want := changedLines{
+		filepath.Join("/root", "a.go"): {{3, 3}, {11, 12}},
+	}
+	opt := cmp.AllowUnexported(lineRange{})
+	if !cmp.Equal(got, want, opt) {
+		t.Errorf("parseDiff() (-want +got):\n%s", cmp.Diff(want, got, opt))
+	}
+	for _, tt := range []struct {
+		line int
+		want bool
+	}{{2, false}, {3, true}, {10, false}, {11, true}, {12, true}, {13, false}} {
+		if got := got.contains(filepath.Join("/root", "a.go"), tt.line); got != tt.want {
+			t.Errorf("contains(a.go, %d) = %t, want %t", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestGitChangedLines(t *testing.T) {
+	setupGit(t)
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com",
+			"commit", "-q", "--allow-empty", "-m", "base"},
+	} {
+		if _, err := git(args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile("a.go", []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := gitChangedLines("HEAD")
+	if err != nil {
+		t.Fatalf("gitChangedLines() = %v", err)
+	}
+	root, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(strings.TrimSpace(string(root)), "a.go")
+	if !got.contains(name, 1) {
+		t.Errorf("gitChangedLines() = %v, want untracked %s changed", got, name)
+	}
+}
+
+func TestGitChangedLinesPrefix(t *testing.T) {
+	setupGit(t)
+	for _, key := range []string{"diff.mnemonicPrefix", "diff.noprefix"} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv("GIT_CONFIG_COUNT", "1")
+			t.Setenv("GIT_CONFIG_KEY_0", key)
+			t.Setenv("GIT_CONFIG_VALUE_0", "true")
+			t.Chdir(t.TempDir())
+			src := "package a\n\nvar x = 1\n"
+			if err := os.WriteFile("a.go", []byte(src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			for _, args := range [][]string{
+				{"init", "-q"},
+				{"add", "a.go"},
+				{"-c", "user.name=test", "-c", "user.email=test@example.com",
+					"commit", "-q", "-m", "base"},
+			} {
+				if _, err := git(args...); err != nil {
+					t.Fatal(err)
+				}
+			}
+			src = strings.Replace(src, "1", "2", 1)
+			if err := os.WriteFile("a.go", []byte(src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := gitChangedLines("HEAD")
+			if err != nil {
+				t.Fatalf("gitChangedLines() = %v", err)
+			}
+			root, err := git("rev-parse", "--show-toplevel")
+			if err != nil {
+				t.Fatal(err)
+			}
+			name := filepath.Join(strings.TrimSpace(string(root)), "a.go")
+			if !got.contains(name, 3) || got.contains(name, 1) {
+				t.Errorf("gitChangedLines() = %v, want only line 3 of %s",
+					got, name,
+				)
+			}
+		})
+	}
+}
+
+func TestGitChangedLinesSymlink(t *testing.T) {
+	setupGit(t)
+	dir := t.TempDir()
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	t.Chdir(link)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com",
+			"commit", "-q", "--allow-empty", "-m", "base"},
+	} {
+		if _, err := git(args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile("a.go", []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := gitChangedLines("HEAD")
+	if err != nil {
+		t.Fatalf("gitChangedLines() = %v", err)
+	}
+	name := filepath.Join(link, "a.go")
+	if !got.contains(name, 1) {
+		t.Errorf("gitChangedLines() = %v, want untracked %s changed", got, name)
+	}
+}
+
+// setupGit skips t if git is not installed, and otherwise keeps the user's
+// and system's git configuration from affecting it.
+func setupGit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+}
+
+func TestSuggestedFixes(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(),
+		NewAnalyzer(lowerNames), "fix",
+	)
+}
+
+// newTestModule copies the Go files of the named package in testdata/src
+// into a module of its own in a temporary directory, which it returns.
+func newTestModule(t *testing.T, pkg string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files, err := filepath.Glob(
+		filepath.Join(analysistest.TestData(), "src", pkg, "*.go"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		buf, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(dir, filepath.Base(name)), buf, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	mod := "module example.com/" + pkg + "\n\ngo 1.25\n"
+	err = os.WriteFile(filepath.Join(dir, "go.mod"), []byte(mod), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestFixFlag(t *testing.T) {
+	if err := flag.Set("checker.fix", "true"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { *fixFlag = false })
+	want, err := os.ReadFile(
+		filepath.Join(analysistest.TestData(), "src", "fix", "fix.go.golden"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(newTestModule(t, "fix"))
+	Run(t, lowerNames)
+	got, err := os.ReadFile("fix.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("fixed fix.go (-want +got):\n%s",
+			cmp.Diff(string(want), string(got)),
+		)
+	}
+}
+
+func TestFixRounds(t *testing.T) {
+	tests := []struct {
+		iterations int
+		want       string
+	}{
+		{0, "var item int"},
+		{1, "var item1 int"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.iterations), func(t *testing.T) {
+			t.Chdir(newTestModule(t, "fixloop"))
+			// The diagnostics left unfixed are warnings, so as not to fail
+			// the test.
+			Config{
+				Fix:           true,
+				FixIterations: tt.iterations,
+				Warn:          []string{"lowernames", "trimdigits"},
+			}.Run(t, lowerNames, trimDigits)
+			buf, err := os.ReadFile("fixloop.go")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(buf)
+			if !strings.Contains(got, "func Used() {}") {
+				t.Errorf("fixed fixloop.go = %q, want Used rolled back", got)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("fixed fixloop.go = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTypeError(t *testing.T) {
+	a := packages.Error{Msg: "a declared and not used"}
+	b := packages.Error{Msg: "undefined: b"}
+	tests := []struct {
+		name          string
+		before, after []packages.Error
+		want          error
+	}{
+		{"none", nil, nil, nil},
+		{"fewer", []packages.Error{a, b}, []packages.Error{a}, nil},
+		{"same", []packages.Error{a}, []packages.Error{a}, nil},
+		{"replaced", []packages.Error{a}, []packages.Error{b}, b},
+		{"repeated", []packages.Error{a}, []packages.Error{a, a}, a},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newTypeError(tt.before, tt.after); got != tt.want {
+				t.Errorf("newTypeError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// helperEnv is the environment variable that names the entry of helpers
+// that TestHelperProcess runs.
+const helperEnv = "CHECKER_TEST_HELPER"
+
+// helpers are runs whose tests are expected to fail, which runHelper starts
+// in a child process so that their failures can be inspected.
+var helpers = map[string]func(t *testing.T){
+	"subtests": func(t *testing.T) {
+		Run(t, dependentAnalyzer, numberedNames, numberedNames)
+	},
+	"baseline": func(t *testing.T) {
+		Config{Baseline: "baseline.json"}.Run(t, publicNames)
+	},
+	"ratchet": func(t *testing.T) {
+		Config{Ratchet: "ratchet.json"}.Run(t, publicNames, numberedNames)
+	},
+	"unusedbuilds": func(t *testing.T) {
+		Run(t, publicNames)
+	},
+	"separate": func(t *testing.T) {
+		Config{Separate: true, Warn: []string{"publicnames"}}.Run(t,
+			publicNames, numberedNames,
+		)
+	},
+}
+
+func TestHelperProcess(t *testing.T) {
+	helper, ok := helpers[os.Getenv(helperEnv)]
+	if !ok {
+		t.Skip("run by runHelper")
+	}
+	helper(t)
+}
+
+// runHelper runs the named entry of helpers in a child process in dir,
+// limited to subtests matching subtest if it is not empty. It returns the
+// output of each test that failed, by name, and whether the child passed.
+func runHelper(t *testing.T, dir, name, subtest string) (map[string]string, bool) {
+	t.Helper()
+	out, ok := execHelper(t, dir, name, subtest)
+	return testOutput(out), ok
+}
+
+// execHelper is like runHelper, but passes flags to the child and returns
+// its combined output as is.
+func execHelper(t *testing.T, dir, name, subtest string, flags ...string) (string, bool) {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := "^TestHelperProcess$"
+	if subtest != "" {
+		run += "/^" + subtest + "$"
+	}
+	cmd := exec.Command(exe, append([]string{"-test.run=" + run}, flags...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), helperEnv+"="+name)
+	out, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		t.Fatalf("running helper %s: %v", name, err)
+	}
+	return string(out), err == nil
+}
+
+// testOutput returns the output of each test that failed, by name, from
+// the output of a test binary run without -test.v, in which the output of
+// each test is indented beneath its --- FAIL line.
+func testOutput(out string) map[string]string {
+	tests := make(map[string]string)
+	type test struct {
+		name   string
+		indent int
+	}
+	var stack []test
+	for line := range strings.Lines(out) {
+		text := strings.TrimLeft(line, " ")
+		indent := len(line) - len(text)
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		if name, ok := strings.CutPrefix(text, "--- FAIL: "); ok {
+			name, _, _ = strings.Cut(name, " ")
+			tests[name] += ""
+			stack = append(stack, test{name, indent})
+		} else if len(stack) > 0 {
+			tests[stack[len(stack)-1].name] += text
+		}
+	}
+	return tests
+}
+
+func TestRunSubtests(t *testing.T) {
+	dir := newTestModule(t, "subtests")
+	messages := []string{
+		"dependent analyzer ran",
+		"item1 has numbers",
+		"Unused is public",
+		"unused //ignore directive",
+	}
+	tests := []struct {
+		subtest string
+		want    map[string][]string
+	}{{
+		// Directive problems and the diagnostics of required analyzers,
+		// such as publicnames, are reported by the test itself. Analyzers
+		// given twice have one subtest.
+		subtest: "",
+		want: map[string][]string{
+			"TestHelperProcess": {
+				"Unused is public", "unused //ignore directive",
+			},
+			"TestHelperProcess/dependent":     {"dependent analyzer ran"},
+			"TestHelperProcess/numberednames": {"item1 has numbers"},
+		},
+	}, {
+		subtest: "numberednames",
+		want: map[string][]string{
+			"TestHelperProcess": {
+				"Unused is public", "unused //ignore directive",
+			},
+			"TestHelperProcess/numberednames": {"item1 has numbers"},
+		},
+	}}
+	for _, tt := range tests {
+		got, ok := runHelper(t, dir, "subtests", tt.subtest)
+		if ok {
+			t.Errorf("helper with subtest %q passed, want failure",
+				tt.subtest,
+			)
+		}
+		if names, want := sortedKeys(got), sortedKeys(tt.want); !cmp.Equal(names, want) {
+			t.Errorf("helper with subtest %q failed %v, want %v",
+				tt.subtest, names, want,
+			)
+		}
+		for name, want := range tt.want {
+			for _, msg := range messages {
+				reported := strings.Contains(got[name], msg)
+				if reported != slices.Contains(want, msg) {
+					t.Errorf("%s reported %q: %t, want %t\n%s",
+						name, msg, reported, !reported, got[name],
+					)
+				}
+			}
+		}
+	}
+}
+
+func TestEditConflicts(t *testing.T) {
+	tests := []struct {
+		a, b edit
+		want bool
+	}{
+		{edit{0, 5, "x"}, edit{0, 5, "x"}, false},
+		{edit{0, 5, "x"}, edit{0, 5, "y"}, true},
+		{edit{0, 5, "x"}, edit{5, 8, "y"}, false},
+		{edit{0, 5, "x"}, edit{4, 8, "y"}, true},
+		{edit{2, 3, "x"}, edit{0, 8, "y"}, true},
+		{edit{5, 5, "x"}, edit{5, 5, "y"}, true},
+		{edit{5, 5, "x"}, edit{0, 5, "y"}, false},
+		{edit{5, 5, "x"}, edit{5, 8, "y"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.a.conflicts(tt.b); got != tt.want {
+			t.Errorf("%v.conflicts(%v) = %t, want %t", tt.a, tt.b, got, tt.want)
+		}
+		if got := tt.b.conflicts(tt.a); got != tt.want {
+			t.Errorf("%v.conflicts(%v) = %t, want %t", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+// A testFile is a source file written for a test, in a file set of its own.
+type testFile struct {
+	*token.File
+	pkg *packages.Package
+}
+
+// newTestFile writes src to the named file and adds it to a new file set.
+func newTestFile(t *testing.T, name, src string) testFile {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	file := fset.AddFile(name, -1, len(src))
+	file.SetLinesForContent([]byte(src))
+	return testFile{file, &packages.Package{PkgPath: "example.com/a", Fset: fset}}
+}
+
+// finding returns a finding of the named analyzer at offset off of f.
+func (f testFile) finding(analyzer string, off int, message string) finding {
+	d := finding{
+		analyzer:   analyzer,
+		pkg:        f.pkg,
+		Diagnostic: analysis.Diagnostic{Pos: f.Pos(off), Message: message},
+	}
+	d.posn = f.pkg.Fset.Position(d.Pos)
+	return d
+}
+
+// rename returns a finding of the named analyzer at the text from, at offset
+// off of f, with a fix that replaces it with to.
+func (f testFile) rename(analyzer string, off int, from, to string) finding {
+	d := f.finding(analyzer, off, from+" is exported")
+	d.End = f.Pos(off + len(from))
+	d.end = f.pkg.Fset.Position(d.End)
+	d.SuggestedFixes = []analysis.SuggestedFix{{
+		Message: "rename to " + to,
+		TextEdits: []analysis.TextEdit{{
+			Pos: d.Pos, End: d.End, NewText: []byte(to),
+		}},
+	}}
+	return d
+}
+
+func TestApplyFixes(t *testing.T) {
+	src := "package a\n\nfunc  F() {}\n\nfunc G() {}\n"
+	file := newTestFile(t, filepath.Join(t.TempDir(), "a.go"), src)
+	f := strings.Index(src, "F")
+	g := strings.Index(src, "G")
+	found := []finding{
+		file.rename("lowernames", f, "F", "f"),
+		file.rename("lowernames", f, "F", "h"), // Conflicts with the first.
+		file.finding("lowernames", f, "F has no fix"),
+		file.rename("lowernames", g, "G", "g"),
+	}
+	report, err := applyFixes(found, nil, func() error { return nil })
+	if err != nil {
+		t.Fatalf("applyFixes() = %v", err)
+	}
+	if want := []bool{true, false, false, true}; !cmp.Equal(report.fixed, want) {
+		t.Errorf("applyFixes() fixed = %v, want %v", report.fixed, want)
+	}
+	if want := []string{file.Name()}; !cmp.Equal(report.changed, want) {
+		t.Errorf("applyFixes() changed = %v, want %v", report.changed, want)
+	}
+	got, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The file is formatted after the fixes are applied.
+	if want := "package a\n\nfunc f() {}\n\nfunc g() {}\n"; string(got) != want {
+		t.Errorf("fixed file = %q, want %q", got, want)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(n int) string {
+		var b strings.Builder
+		for i := 1; i <= n; i++ {
+			fmt.Fprintf(&b, "line %d\n", i)
+		}
+		return b.String()
+	}
+	tests := []struct {
+		name          string
+		before, after string
+		want          string
+	}{{
+		name:   "change",
+		before: "a\nb\nc\n",
+		after:  "a\nB\nc\n",
+		want: "--- f.go\n+++ f.go\n" +
+			"@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+	}, {
+		name:   "insert",
+		before: "a\n",
+		after:  "a\nb\n",
+		want:   "--- f.go\n+++ f.go\n@@ -1,1 +1,2 @@\n a\n+b\n",
+	}, {
+		name:   "no newline",
+		before: "a",
+		after:  "b",
+		want: "--- f.go\n+++ f.go\n@@ -1,1 +1,1 @@\n" +
+			"-a\n\\ No newline at end of file\n" +
+			"+b\n\\ No newline at end of file\n",
+	}, {
+		name:   "two hunks",
+		before: lines(20),
+		after: strings.Replace(strings.Replace(lines(20),
+			"line 2\n", "two\n", 1), "line 19\n", "nineteen\n", 1),
+		want: "--- f.go\n+++ f.go\n" +
+			"@@ -1,5 +1,5 @@\n line 1\n-line 2\n+two\n" +
+			" line 3\n line 4\n line 5\n" +
+			"@@ -16,5 +16,5 @@\n line 16\n line 17\n line 18\n" +
+			"-line 19\n+nineteen\n line 20\n",
+	}, {
+		name:   "merged hunks",
+		before: lines(10),
+		after: strings.Replace(strings.Replace(lines(10),
+			"line 2\n", "two\n", 1), "line 8\n", "eight\n", 1),
+		want: "--- f.go\n+++ f.go\n" +
+			"@@ -1,10 +1,10 @@\n line 1\n-line 2\n+two\n" +
+			" line 3\n line 4\n line 5\n line 6\n line 7\n" +
+			"-line 8\n+eight\n line 9\n line 10\n",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedDiff("f.go", tt.before, tt.after)
+			if got != tt.want {
+				t.Errorf("unifiedDiff() (-want +got):\n%s",
+					cmp.Diff(tt.want, got),
+				)
+			}
+		})
+	}
+}
+
+func TestApplyFixesRollback(t *testing.T) {
+	src := "package a\n\nfunc F() {}\n\nfunc G() {}\n\nfunc H() {}\n"
+	file := newTestFile(t, filepath.Join(t.TempDir(), "a.go"), src)
+	rename := func(from, to string) finding {
+		return file.rename("lowernames", strings.Index(src, from), from, to)
+	}
+	found := []finding{rename("F", "f"), rename("G", "bad"), rename("H", "h")}
+	var checks int
+	report, err := applyFixes(found, nil, func() error {
+		checks++
+		got, err := os.ReadFile(file.Name())
+		if err != nil {
+			return err
+		}
+		if strings.Contains(string(got), "bad") {
+			return fmt.Errorf("bad")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("applyFixes() = %v", err)
+	}
+	if want := []bool{true, false, true}; !cmp.Equal(report.fixed, want) {
+		t.Errorf("applyFixes() fixed = %v, want %v", report.fixed, want)
+	}
+	if _, ok := report.broken[1]; !ok || len(report.broken) != 1 {
+		t.Errorf("applyFixes() broken = %v, want only 1", report.broken)
+	}
+	if want := 4; checks != want {
+		t.Errorf("check called %d times, want %d", checks, want)
+	}
+	got, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package a\n\nfunc f() {}\n\nfunc G() {}\n\nfunc h() {}\n"
+	if string(got) != want {
+		t.Errorf("fixed file = %q, want %q", got, want)
+	}
+}
+
+func TestApplyFixesPriority(t *testing.T) {
+	src := "package a\n\nfunc F() {}\n"
+	file := newTestFile(t, filepath.Join(t.TempDir(), "a.go"), src)
+	off := strings.Index(src, "F")
+	found := []finding{
+		file.rename("first", off, "F", "a"),
+		file.rename("unlisted", off, "F", "b"),
+		file.rename("second", off, "F", "c"),
+	}
+	report, err := applyFixes(found, []string{"second", "first"},
+		func() error { return nil },
+	)
+	if err != nil {
+		t.Fatalf("applyFixes() = %v", err)
+	}
+	if want := []bool{false, false, true}; !cmp.Equal(report.fixed, want) {
+		t.Errorf("applyFixes() fixed = %v, want %v", report.fixed, want)
+	}
+	if want := map[int]int{0: 2, 1: 2}; !cmp.Equal(report.skipped, want) {
+		t.Errorf("applyFixes() skipped = %v, want %v", report.skipped, want)
+	}
+	got, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "package a\n\nfunc c() {}\n"; string(got) != want {
+		t.Errorf("fixed file = %q, want %q", got, want)
+	}
+}
+
+func TestTagNolint(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(),
+		NewAnalyzer(publicNames, numberedNames), "tag",
+	)
+}
+
+func TestTag(t *testing.T) {
+	diag := analysis.Diagnostic{Category: "format", Message: "bad verb"}
+	tests := []struct {
+		tag                   Tag
+		category, message     string
+		uncategorizedCategory string
+	}{
+		{TagBoth, "printf.format", "[printf] bad verb", "printf"},
+		{TagCategory, "printf.format", "bad verb", "printf"},
+		{TagMessage, "format", "[printf] bad verb", ""},
+		{TagNone, "format", "bad verb", ""},
+	}
+	for _, tt := range tests {
+		got := tt.tag.apply("printf", diag)
+		if got.Category != tt.category || got.Message != tt.message {
+			t.Errorf("Tag(%d).apply() = %q, %q, want %q, %q", tt.tag,
+				got.Category, got.Message, tt.category, tt.message,
+			)
+		}
+		plain := diag
+		plain.Category = ""
+		if got := tt.tag.apply("printf", plain); got.Category != tt.uncategorizedCategory {
+			t.Errorf("Tag(%d).apply() category = %q, want %q", tt.tag,
+				got.Category, tt.uncategorizedCategory,
+			)
+		}
+	}
+}
+
+func TestPrintError(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	src := "package a\n\nfunc f() {\n\tx := \"é\"; y := 1\n}\n"
+	file := newTestFile(t, filepath.Join(dir, "a.go"), src)
+	f := file.finding("shortnames", strings.Index(src, "y"),
+		"y is single letter",
+	)
+	var buf bytes.Buffer
+	printError(&buf, make(sources), f)
+	want := "a.go:4:13: [shortnames] y is single letter\n" +
+		"\t\tx := \"é\"; y := 1\n" +
+		"\t\t          ^\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printError() (-want +got):\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestWarn(t *testing.T) {
+	tests := []struct {
+		pkg       string
+		analyzers []*analysis.Analyzer
+		want      []string
+	}{{
+		pkg:       "multiple",
+		analyzers: []*analysis.Analyzer{publicNames, numberedNames},
+		want: []string{
+			"error: item2 has numbers",
+			"warning: AnotherPublic is public",
+		},
+	}, {
+		// publicnames is both run and required by dependent, and reports
+		// each of its diagnostics once, as a warning.
+		pkg:       "dependency",
+		analyzers: []*analysis.Analyzer{publicNames, dependentAnalyzer},
+		want: []string{
+			"error: dependent analyzer ran",
+			"warning: PublicVar is public",
+			"warning: TestFunc is public",
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.pkg, func(t *testing.T) {
+			c := Config{Warn: []string{"publicnames"}}
+			results := analysistest.Run(t, analysistest.TestData(),
+				c.NewAnalyzer(tt.analyzers...), tt.pkg,
+			)
+			var got []string
+			for _, r := range results {
+				for _, d := range r.Action.Result.(*result).diags {
+					got = append(got, d.severity.String()+": "+d.Message)
+				}
+			}
+			slices.Sort(got)
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("reported diagnostics (-want +got):\n%s",
+					cmp.Diff(tt.want, got),
+				)
+			}
+		})
+	}
+}
+
+func TestJSONReport(t *testing.T) {
+	root := t.TempDir()
+	src := "package a\n\nfunc F() {}\n"
+	file := newTestFile(t, filepath.Join(root, "a", "a.go"), src)
+	off := strings.Index(src, "F")
+	f := file.rename("lowernames", off, "F", "f")
+	f.severity = severityWarning
+	f.Category = "func"
+	f.URL = "https://example.com/lowernames"
+	f.Related = []analysis.RelatedInformation{{
+		Pos:     file.Pos(0),
+		Message: "in package a",
+	}}
+	found := []finding{f}
+	rng := jsonRange{
+		File:  "a/a.go",
+		Start: jsonPosition{3, 6, off},
+		End:   jsonPosition{3, 7, off + 1},
+	}
+	want := jsonReport{
+		Diagnostics: []jsonDiagnostic{{
+			Analyzer: "lowernames",
+			Severity: "warning",
+			Package:  "example.com/a",
+			Range:    rng,
+			Message:  "F is exported",
+			Category: "func",
+			URL:      "https://example.com/lowernames",
+			Related: []jsonRelated{{
+				Range: jsonRange{
+					File:  "a/a.go",
+					Start: jsonPosition{1, 1, 0},
+					End:   jsonPosition{1, 1, 0},
+				},
+				Message: "in package a",
+			}},
+			SuggestedFixes: []jsonFix{{
+				Message: "rename to f",
+				Edits:   []jsonEdit{{Range: rng, NewText: "f"}},
+			}},
+		}},
+		Errors: []string{"checker: failed"},
+	}
+	report := filepath.Join(root, "report.json")
+	err := writeJSONReport(report, root, found, []error{fmt.Errorf("checker: failed")})
+	if err != nil {
+		t.Fatalf("writeJSONReport() = %v", err)
+	}
+	buf, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got jsonReport
+	if err := json.Unmarshal(buf, &got); err != nil {
+		t.Fatalf("bad JSON report: %v", err)
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("JSON report (-want +got):\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestSuppressedReasons(t *testing.T) {
+	results := analysistest.Run(t, analysistest.TestData(),
+		Config{RequireReason: true}.NewAnalyzer(publicNames), "reason",
+	)
+	var got []string
+	for _, r := range results {
+		for _, d := range r.Action.Result.(*result).suppressed {
+			for _, s := range d.by {
+				got = append(got, d.Message+": "+s.kind+": "+s.reason)
+			}
+		}
+	}
+	slices.Sort(got)
+	want := []string{
+		"AnotherPublic is public: //ignore: ",
+		"EmptyReason is public: //ignore: ",
+		"InlinePublic is public: //ignore: part of the public API",
+		"PublicFunc is public: //ignore: exported for compatibility",
+		"UnjustifiedPublic is public: //ignore: ",
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("suppressed diagnostics (-want +got):\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	root := t.TempDir()
+	src := "package a\n\n// é\nfunc F() {}\n"
+	file := newTestFile(t, filepath.Join(root, "a", "a.go"), src)
+	found := []finding{
+		file.rename("lowernames", strings.Index(src, "F"), "F", "f"),
+	}
+	accent := file.finding("accents", strings.Index(src, "é")+len("é"),
+		"comment has accents",
+	)
+	accent.severity = severityWarning
+	accent.suppressions = []suppression{{"//ignore", "it is French"}}
+	suppressed := []finding{accent}
+	log := newSARIFLog(root, []*analysis.Analyzer{lowerNames}, found,
+		suppressed, nil,
+	)
+	if len(log.Runs) != 1 {
+		t.Fatalf("got %d runs, want 1", len(log.Runs))
+	}
+	run := log.Runs[0]
+	var rules []string
+	for _, r := range run.Tool.Driver.Rules {
+		rules = append(rules, r.ID)
+	}
+	if want := []string{"checker", "lowernames", "accents"}; !cmp.Equal(rules, want) {
+		t.Errorf("rules = %v, want %v", rules, want)
+	}
+	if got, want := run.Tool.Driver.Rules[1].FullDescription,
+		(&sarifMessage{lowerNames.Doc}); !cmp.Equal(got, want) {
+		t.Errorf("lowernames description = %v, want %v", got, want)
+	}
+	region := sarifRegion{StartLine: 4, StartColumn: 6, EndLine: 4, EndColumn: 7}
+	loc := sarifArtifactLocation{URI: "a/a.go", URIBaseID: srcRoot}
+	want := []sarifResult{{
+		RuleID:    "lowernames",
+		RuleIndex: 1,
+		Level:     "error",
+		Message:   sarifMessage{"F is exported"},
+		Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: loc,
+			Region:           region,
+		}}},
+		Fixes: []sarifFix{{
+			Description: sarifMessage{"rename to f"},
+			ArtifactChanges: []sarifArtifactChange{{
+				ArtifactLocation: loc,
+				Replacements: []sarifReplacement{{
+					DeletedRegion:   region,
+					InsertedContent: sarifMessage{"f"},
+				}},
+			}},
+		}},
+	}, {
+		RuleID:    "accents",
+		RuleIndex: 2,
+		Level:     "warning",
+		Message:   sarifMessage{"comment has accents"},
+		Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: loc,
+			// The column counts UTF-16 code units, not bytes.
+			Region: sarifRegion{
+				StartLine: 3, StartColumn: 5, EndLine: 3, EndColumn: 5,
+			},
+		}}},
+		Suppressions: []sarifSuppression{{
+			Kind:          "inSource",
+			Justification: "it is French",
+		}},
+	}}
+	if !cmp.Equal(run.Results, want) {
+		t.Errorf("results (-want +got):\n%s", cmp.Diff(want, run.Results))
+	}
+	if got, want := run.OriginalURIBaseIDs[srcRoot].URI,
+		fileURI(root)+"/"; got != want {
+		t.Errorf("%s = %q, want %q", srcRoot, got, want)
+	}
+}
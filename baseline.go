@@ -0,0 +1,153 @@
+package checker
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// A baselineEntry records a known diagnostic in a baseline file.
+type baselineEntry struct {
+	Fingerprint string `json:"fingerprint"`
+	Analyzer    string `json:"analyzer"`
+	File        string `json:"file"`
+	Message     string `json:"message"`
+}
+
+// fingerprint identifies a diagnostic by its analyzer, file, message and the
+// text of the line it is on, rather than by its position, so that it survives
+// edits elsewhere in the file.
+func fingerprint(analyzer, file, message, line string) string {
+	h := sha256.New()
+	for _, s := range []string{analyzer, file, message, strings.TrimSpace(line)} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// newBaseline returns a baseline entry for each finding, in order. File names
+// are relative to root.
+func newBaseline(found []finding, root string) []baselineEntry {
+	src := make(sources)
+	entries := make([]baselineEntry, len(found))
+	for i, f := range found {
+		file := f.posn.Filename
+		if rel, err := filepath.Rel(root, file); err == nil {
+			file = filepath.ToSlash(rel)
+		}
+		line, _ := src.line(f.posn.Filename, f.posn.Line)
+		entries[i] = baselineEntry{
+			Fingerprint: fingerprint(f.analyzer, file, f.Message, line),
+			Analyzer:    f.analyzer,
+			File:        file,
+			Message:     f.Message,
+		}
+	}
+	return entries
+}
+
+// diffBaseline compares the current entries against a recorded baseline. It
+// returns the indices of current entries not in the baseline, and the
+// recorded entries that no longer occur. Entries with equal fingerprints are
+// counted, so a second identical diagnostic on another line is new.
+func diffBaseline(current, recorded []baselineEntry) (added []int, fixed []baselineEntry) {
+	counts := make(map[string]int)
+	for _, e := range recorded {
+		counts[e.Fingerprint]++
+	}
+	for i, e := range current {
+		if counts[e.Fingerprint] > 0 {
+			counts[e.Fingerprint]--
+		} else {
+			added = append(added, i)
+		}
+	}
+	for _, e := range recorded {
+		if counts[e.Fingerprint] > 0 {
+			counts[e.Fingerprint]--
+			fixed = append(fixed, e)
+		}
+	}
+	return added, fixed
+}
+
+// readBaseline reads the baseline file at name. A missing file is an empty
+// baseline.
+func readBaseline(name string) ([]baselineEntry, error) {
+	buf, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var entries []baselineEntry
+	if err := json.Unmarshal(buf, &entries); err != nil {
+		return nil, fmt.Errorf("bad baseline %s: %w", name, err)
+	}
+	return entries, nil
+}
+
+// writeBaseline writes entries to the baseline file at name, sorted so that
+// the file changes as little as possible between runs.
+func writeBaseline(name string, entries []baselineEntry) error {
+	entries = slices.Clone(entries)
+	slices.SortFunc(entries, func(a, b baselineEntry) int {
+		return cmp.Or(
+			cmp.Compare(a.File, b.File),
+			cmp.Compare(a.Analyzer, b.Analyzer),
+			cmp.Compare(a.Message, b.Message),
+			cmp.Compare(a.Fingerprint, b.Fingerprint),
+		)
+	})
+	if entries == nil {
+		entries = []baselineEntry{}
+	}
+	buf, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(buf, '\n'), 0o644)
+}
+
+// checkBaseline returns the findings not recorded in c.Baseline, and fails
+// the test for recorded entries that no longer occur. If c.UpdateBaseline is
+// set, it records found in c.Baseline instead and returns nothing.
+func (c Config) checkBaseline(t testingT, root string, found []finding) []finding {
+	t.Helper()
+	current := newBaseline(found, root)
+	if c.UpdateBaseline {
+		if err := writeBaseline(c.Baseline, current); err != nil {
+			t.Fatalf("failed to write baseline: %v", err)
+		}
+		t.Logf("recorded %d diagnostics in %s", len(current), c.Baseline)
+		return nil
+	}
+	recorded, err := readBaseline(c.Baseline)
+	if err != nil {
+		t.Fatalf("failed to read baseline: %v", err)
+	}
+	added, fixed := diffBaseline(current, recorded)
+	if len(fixed) > 0 {
+		var b strings.Builder
+		for _, e := range fixed {
+			fmt.Fprintf(&b, "%s: [%s] %s\n", e.File, e.Analyzer, e.Message)
+		}
+		t.Errorf("%d baseline entries no longer occur; update %s\n%s",
+			len(fixed), c.Baseline, b.String(),
+		)
+	}
+	fresh := make([]finding, len(added))
+	for i, j := range added {
+		fresh[i] = found[j]
+	}
+	return fresh
+}
@@ -16,21 +16,334 @@ package checker
 
 import (
 	"bytes"
+	"fmt"
+	"os"
+	"slices"
 	"testing"
+	"time"
 
 	"golang.org/x/tools/go/analysis"
 	gochecker "golang.org/x/tools/go/analysis/checker"
 	"golang.org/x/tools/go/packages"
 )
 
+// Config configures how analyzers are run and how //ignore directives are
+// interpreted. The zero Config is what [Run] and [NewAnalyzer] use.
+type Config struct {
+	// RequireReason reports //ignore directives that do not give a
+	// reason after "--", as in:
+	//
+	//	//ignore:errcheck -- close error is irrelevant here
+	RequireReason bool
+
+	// Now returns the current time, against which the expiry dates of
+	// //ignore directives are checked. It defaults to [time.Now].
+	//
+	// A directive with an expiry date stops suppressing diagnostics once
+	// that day has passed, and is reported instead:
+	//
+	//	//ignore:nilness until=2026-12-31
+	Now func() time.Time
+
+	// Nolint recognizes golangci-lint directives alongside //ignore, such
+	// as //nolint:errcheck. Text following a // after the directive is
+	// taken as its reason.
+	Nolint bool
+
+	// LintIgnore recognizes staticcheck directives alongside //ignore, such
+	// as //lint:ignore SA1019 reason and //lint:file-ignore SA1019 reason.
+	LintIgnore bool
+
+	// Groups defines named groups of analyzers, which directives may refer
+	// to in place of the analyzers themselves. Given
+	//
+	//	Groups: map[string][]string{"style": {"linelen", "tidytypes"}}
+	//
+	// a directive of //ignore:style suppresses both linelen and tidytypes.
+	// Groups may include other groups, but not themselves.
+	Groups map[string][]string
+
+	// KnownAnalyzers names analyzers that directives may refer to besides
+	// those being run, such as those run by another [Run] in the same
+	// package. A directive that names any other analyzer is reported, as
+	// the name is likely a typo. Given
+	//
+	//	func TestCheck(t *testing.T) {
+	//	    checker.Config{KnownAnalyzers: []string{"linelen"}}.
+	//	        Run(t, errcheck.Analyzer)
+	//	}
+	//
+	//	func TestStyle(t *testing.T) {
+	//	    checker.Config{KnownAnalyzers: []string{"errcheck"}}.
+	//	        Run(t, linelen.Analyzer)
+	//	}
+	//
+	// neither test reports //ignore:linelen or //ignore:errcheck as unknown.
+	KnownAnalyzers []string
+
+	// Warn names analyzers whose diagnostics are warnings. [Run] logs
+	// warnings instead of failing the test, so that an analyzer can be
+	// visible without blocking:
+	//
+	//	checker.Config{Warn: []string{"linelen"}}.
+	//	    Run(t, errcheck.Analyzer, linelen.Analyzer)
+	Warn []string
+
+	// Ignore suppresses diagnostics by path, analyzer and message. [Run]
+	// adds any rules in a checker.json file at the module root.
+	Ignore []Rule
+
+	// Baseline is the path of a baseline file, relative to the package
+	// directory. Diagnostics recorded in the baseline do not fail the test,
+	// but recorded diagnostics that no longer occur do, so that the baseline
+	// is kept up to date as they are fixed. A missing file is an empty
+	// baseline.
+	//
+	// Diagnostics are recorded by analyzer, file, message and the text of
+	// their line, so that they are still recognized after unrelated edits.
+	Baseline string
+
+	// UpdateBaseline records the current diagnostics in the Baseline file,
+	// replacing its contents, instead of checking them.
+	UpdateBaseline bool
+
+	// Ratchet is the path of a ratchet file, relative to the package
+	// directory, which records how many diagnostics each analyzer reports
+	// in each package. The test fails if any count rises above its
+	// recorded budget, and reports the diagnostics of that analyzer and
+	// package. A missing file has no budget for anything.
+	//
+	// Counts that fall are logged, so that the file can be rewritten with
+	// UpdateRatchet and the lower counts become the new budgets.
+	Ratchet string
+
+	// UpdateRatchet records the current counts in the Ratchet file,
+	// replacing its contents, instead of checking them.
+	UpdateRatchet bool
+
+	// ChangedSince restricts diagnostics to lines added or modified since
+	// the merge base of this git ref and HEAD, such as "origin/main",
+	// including uncommitted changes. The diff is taken with the local git
+	// binary. [Run] takes ChangedSince from the CHECKER_CHANGED_SINCE
+	// environment variable if it is not set.
+	ChangedSince string
+
+	// Fix applies the suggested fixes of the diagnostics that are
+	// reported, in the manner of go vet -fix, and formats the files it
+	// changes. Where the fixes of several analyzers conflict, the analyzer
+	// given first to [Run] wins, and the other fixes are skipped and logged.
+	// A fix that stops the package from type-checking is rolled back.
+	// The analyzers are then run again on the fixed code, and their fixes
+	// applied in turn, until none apply. The -checker.fix test flag sets
+	// Fix for every [Run]:
+	//
+	//	go test -run TestCheck -checker.fix
+	Fix bool
+
+	// FixIterations limits the rounds of fixes that Fix applies. It
+	// defaults to 10.
+	FixIterations int
+
+	// Tag selects how diagnostics forwarded by the analyzer from
+	// [Config.NewAnalyzer] name the analyzer that produced them, since
+	// drivers such as go vet and gopls attribute them all to "checker". By
+	// default, both the category and the message name it.
+	Tag Tag
+
+	// Separate reports each diagnostic as an error of its own, in the form
+	// of a compiler error that editors and IDEs link to the source,
+	// followed by its line with a caret under its column:
+	//
+	//	x.go:12:2: [errcheck] error return value not checked
+	//		f.Close()
+	//		^
+	Separate bool
+
+	// JSONReport is the path of a file, relative to the package
+	// directory, to which [Run] writes a JSON report of every diagnostic
+	// and error, whether or not the test fails. It reports the analyzer,
+	// severity, package, position, message, category, URL, related
+	// information and suggested fixes of each diagnostic, before any
+	// baseline or ratchet is applied. [Run] takes JSONReport from the
+	// CHECKER_JSON_REPORT environment variable if it is not set.
+	JSONReport string
+
+	// SARIF is the path of a file, relative to the package directory, to
+	// which [Run] writes a SARIF 2.1.0 log, whether or not the test fails.
+	// The log has a rule for each analyzer and a result for each
+	// diagnostic, including those suppressed by directives or rules, whose
+	// reasons are given as the justification of their suppressions. [Run]
+	// takes SARIF from the CHECKER_SARIF environment variable if it is not
+	// set.
+	SARIF string
+
+	// changed returns the lines to which ChangedSince restricts diagnostics.
+	changed func() (changedLines, error)
+}
+
 // Run runs analyzers against the current package.
 //
 // If the analyzers produce diagnostics, or fail to run, the test will fail.
+// Each analyzer reports its diagnostics in a subtest named after it, so that
+//
+//	go test -run TestCheck/errcheck
+//
+// shows only those of errcheck.
 func Run(t *testing.T, analyzers ...*analysis.Analyzer) {
-	run(testingT{t}, analyzers...)
+	t.Helper()
+	Config{}.Run(t, analyzers...)
+}
+
+// Run runs analyzers against the current package, as configured by c.
+//
+// If the analyzers produce diagnostics, or fail to run, the test will fail.
+func (c Config) Run(t *testing.T, analyzers ...*analysis.Analyzer) {
+	t.Helper()
+	c.run(testingT{t}, analyzers...)
+}
+
+func (c Config) run(t testingT, analyzers ...*analysis.Analyzer) {
+	t.Helper()
+	root, err := moduleRoot(".")
+	if err != nil {
+		t.Fatalf("failed to find module root: %v", err)
+	}
+	rules, err := loadRules(root)
+	if err != nil {
+		t.Fatalf("failed to load %s: %v", rulesFile, err)
+	}
+	c.Ignore = append(slices.Clip(c.Ignore), rules...)
+	if c.ChangedSince == "" {
+		c.ChangedSince = os.Getenv(changedSinceEnv)
+	}
+	if c.JSONReport == "" {
+		c.JSONReport = os.Getenv(jsonReportEnv)
+	}
+	if c.SARIF == "" {
+		c.SARIF = os.Getenv(sarifEnv)
+	}
+
+	var (
+		found      []finding
+		suppressed []finding
+		errs       []error
+		fixing     = c.Fix || *fixFlag
+	)
+	priority := make([]string, len(analyzers))
+	for i, a := range analyzers {
+		priority[i] = a.Name
+	}
+	for round := 0; ; round++ {
+		found, suppressed, errs = c.analyze(t, analyzers)
+		if !fixing || round == c.fixIterations() ||
+			fixRound(t, found, priority) == 0 {
+			break
+		}
+	}
+	if c.JSONReport != "" {
+		if err := writeJSONReport(c.JSONReport, root, found, errs); err != nil {
+			t.Fatalf("failed to write JSON report: %v", err)
+		}
+	}
+	if c.SARIF != "" {
+		err := writeSARIF(c.SARIF, root, analyzers, found, suppressed, errs)
+		if err != nil {
+			t.Fatalf("failed to write SARIF log: %v", err)
+		}
+	}
+
+	// Warnings never fail the test, so they are kept out of the baseline
+	// and the ratchet.
+	var warned []finding
+	found = slices.DeleteFunc(found, func(f finding) bool {
+		if f.severity == severityWarning {
+			warned = append(warned, f)
+			return true
+		}
+		return false
+	})
+	if c.Baseline != "" {
+		found = c.checkBaseline(t, root, found)
+	}
+	if c.Ratchet != "" {
+		found = c.checkRatchet(t, found)
+	}
+	found = append(found, warned...)
+	sortFindings(found)
+
+	byAnalyzer := make(map[string][]finding)
+	for _, f := range found {
+		byAnalyzer[f.analyzer] = append(byAnalyzer[f.analyzer], f)
+	}
+	seen := make(map[string]bool)
+	for _, a := range analyzers {
+		if seen[a.Name] {
+			continue
+		}
+		seen[a.Name] = true
+		found := byAnalyzer[a.Name]
+		t.Run(a.Name, func(t testingT) {
+			t.Helper()
+			c.report(t, nil, found)
+		})
+	}
+	// Report what remains, such as problems with directives and the
+	// diagnostics of required analyzers, in the test itself.
+	found = slices.DeleteFunc(found, func(f finding) bool {
+		return seen[f.analyzer]
+	})
+	c.report(t, errs, found)
 }
 
-func run(t testingT, analyzers ...*analysis.Analyzer) {
+// report fails the test if there are any errors or findings, other than
+// warnings, which it logs.
+func (c Config) report(t testingT, errs []error, found []finding) {
+	t.Helper()
+	var failed, warned []finding
+	for _, f := range found {
+		if f.severity == severityWarning {
+			warned = append(warned, f)
+		} else {
+			failed = append(failed, f)
+		}
+	}
+	if c.Separate {
+		for _, err := range errs {
+			t.Errorf("%v", err)
+		}
+		// Begin each error with its position, unprefixed, so that it
+		// reads as a compiler error.
+		src := make(sources)
+		for _, f := range found {
+			var buf bytes.Buffer
+			printError(&buf, src, f)
+			if f.severity == severityWarning {
+				t.T.Logf("%s", buf.String())
+			} else {
+				t.T.Errorf("%s", buf.String())
+			}
+		}
+		return
+	}
+	if len(warned) > 0 {
+		var buf bytes.Buffer
+		printFindings(&buf, warned)
+		t.Logf("check warnings\n%v", buf.String())
+	}
+	var buf bytes.Buffer
+	for _, err := range errs {
+		fmt.Fprintln(&buf, err)
+	}
+	printFindings(&buf, failed)
+	if buf.Len() > 0 {
+		t.Errorf("check failed\n%v", buf.String())
+	}
+}
+
+// analyze runs analyzers against the current package and returns their
+// findings, those that were suppressed, and their errors.
+func (c Config) analyze(t testingT, analyzers []*analysis.Analyzer) (found, suppressed []finding, errs []error) {
+	t.Helper()
 	pkgs, err := packages.Load(&packages.Config{
 		Mode:  packages.LoadAllSyntax,
 		Tests: true,
@@ -40,31 +353,35 @@ func run(t testingT, analyzers ...*analysis.Analyzer) {
 	}
 
 	graph, err := gochecker.Analyze(
-		[]*analysis.Analyzer{NewAnalyzer(analyzers...)}, pkgs, nil,
+		[]*analysis.Analyzer{c.NewAnalyzer(analyzers...)}, pkgs, nil,
 	)
 	if err != nil {
 		t.Fatalf("failed to run analyzers: %v", err)
 	}
-
-	var buf bytes.Buffer
-	if err := graph.PrintText(&buf, 0); err != nil {
-		t.Fatalf("failed to print diagnostics: %v", err)
-	}
-	if buf.Len() > 0 {
-		t.Errorf("check failed\n%v", buf.String())
-	}
+	return findings(graph)
 }
 
 type testingT struct{ *testing.T }
 
+func (t testingT) Run(name string, f func(t testingT)) bool {
+	t.Helper()
+	return t.T.Run(name, func(t *testing.T) {
+		t.Helper()
+		f(testingT{t})
+	})
+}
+
 func (t testingT) Fatalf(format string, args ...any) {
+	t.Helper()
 	t.T.Fatalf("[lesiw.io/checker] "+format, args...)
 }
 
 func (t testingT) Errorf(format string, args ...any) {
+	t.Helper()
 	t.T.Errorf("[lesiw.io/checker] "+format, args...)
 }
 
 func (t testingT) Logf(format string, args ...any) {
+	t.Helper()
 	t.T.Logf("[lesiw.io/checker] "+format, args...)
 }
@@ -2,6 +2,8 @@ package checker
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"golang.org/x/tools/go/analysis"
@@ -57,3 +59,43 @@ func detectCycles(analyzers []*analysis.Analyzer) error {
 	}
 	return nil
 }
+
+func detectGroupCycles(groups map[string][]string) error {
+	var (
+		visited  = make(map[string]struct{})
+		recStack = make(map[string]struct{})
+		visit    func(string, []string) error
+	)
+	visit = func(g string, path []string) error {
+		if _, inStack := recStack[g]; inStack {
+			cycle := append(path, g)
+			return fmt.Errorf(
+				"circular group definition detected: %s",
+				strings.Join(cycle, " -> "),
+			)
+		}
+		if _, wasVisited := visited[g]; wasVisited {
+			return nil
+		}
+		visited[g] = struct{}{}
+		recStack[g] = struct{}{}
+		newPath := append(path, g)
+		for _, member := range groups[g] {
+			if _, isGroup := groups[member]; !isGroup {
+				continue
+			}
+			if err := visit(member, newPath); err != nil {
+				return err
+			}
+		}
+		delete(recStack, g)
+		return nil
+	}
+
+	for _, g := range slices.Sorted(maps.Keys(groups)) {
+		if err := visit(g, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package checker
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// changedSinceEnv is the environment variable from which [Run] takes
+// Config.ChangedSince, if it is not set.
+const changedSinceEnv = "CHECKER_CHANGED_SINCE"
+
+// changedLines holds the lines added or modified in each file, keyed by
+// absolute file name with symbolic links resolved. A nil changedLines
+// includes every line.
+type changedLines map[string][]lineRange
+
+// A lineRange is a range of lines, counting from 1, inclusive.
+type lineRange struct{ start, end int }
+
+// contains reports whether line of the named file was changed.
+func (c changedLines) contains(filename string, line int) bool {
+	if c == nil {
+		return true
+	}
+	if name, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = name
+	}
+	for _, r := range c[filename] {
+		if r.start <= line && line <= r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// gitChangedLines returns the lines changed in the working tree since the
+// merge base of ref and HEAD, including uncommitted changes. Files unknown
+// to git are changed in their entirety.
+func gitChangedLines(ref string) (changedLines, error) {
+	top, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.EvalSymlinks(strings.TrimSpace(string(top)))
+	if err != nil {
+		return nil, err
+	}
+	base, err := git("merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	// The prefixes are given explicitly, since parseDiff expects them and
+	// diff.noprefix and diff.mnemonicPrefix would otherwise change them.
+	out, err := git("diff", "--unified=0", "--no-color", "--no-ext-diff",
+		"--no-renames", "--src-prefix=a/", "--dst-prefix=b/",
+		strings.TrimSpace(string(base)), "--",
+	)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := parseDiff(root, out)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git("ls-files", "--others", "--exclude-standard",
+		"--full-name", "--", root,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for name := range strings.Lines(string(untracked)) {
+		name = filepath.Join(root, filepath.FromSlash(strings.TrimSpace(name)))
+		changed[name] = []lineRange{{1, math.MaxInt}}
+	}
+	return changed, nil
+}
+
+// git runs git with args and returns its standard output.
+func git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s",
+			strings.Join(args, " "), err, bytes.TrimSpace(stderr.Bytes()),
+		)
+	}
+	return out, nil
+}
+
+// hunkRe matches the header of a hunk in a unified diff, capturing the range
+// of lines in the new file.
+var hunkRe = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// parseDiff returns the lines added or modified by a unified diff, whose file
+// names are relative to root.
+func parseDiff(root string, diff []byte) (changedLines, error) {
+	changed := make(changedLines)
+	var file string
+	sc := bufio.NewScanner(bytes.NewReader(diff))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if name, ok := strings.CutPrefix(line, "+++ "); ok {
+			file = ""
+			if name, ok := strings.CutPrefix(name, "b/"); ok {
+				file = filepath.Join(root, filepath.FromSlash(name))
+			}
+			continue
+		}
+		m := hunkRe.FindStringSubmatch(line)
+		if m == nil || file == "" {
+			continue
+		}
+		start, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("bad hunk header %q: %w", line, err)
+		}
+		count := 1
+		if m[2] != "" {
+			if count, err = strconv.Atoi(m[2]); err != nil {
+				return nil, fmt.Errorf("bad hunk header %q: %w", line, err)
+			}
+		}
+		if count > 0 {
+			changed[file] = append(changed[file],
+				lineRange{start, start + count - 1},
+			)
+		}
+	}
+	return changed, sc.Err()
+}
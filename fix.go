@@ -0,0 +1,369 @@
+package checker
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// fixFlag is the -checker.fix test flag, which sets Config.Fix for every
+// Run. It is only registered in test binaries, so that other programs that
+// import checker, such as vet tools, do not list it among their flags.
+var fixFlag = new(bool)
+
+func init() {
+	if testing.Testing() {
+		flag.BoolVar(fixFlag, "checker.fix", false,
+			"apply suggested fixes from analyzers run by lesiw.io/checker",
+		)
+	}
+}
+
+// defaultFixIterations is the number of rounds of fixes applied when
+// Config.FixIterations is not set.
+const defaultFixIterations = 10
+
+func (c Config) fixIterations() int {
+	if c.FixIterations > 0 {
+		return c.FixIterations
+	}
+	return defaultFixIterations
+}
+
+// fixRound applies the suggested fixes of found, with priority given to
+// analyzers in the order of priority, rolling back those that stop the
+// current package from type-checking. It returns how many it applied.
+func fixRound(t testingT, found []finding, priority []string) int {
+	t.Helper()
+	before, err := typeErrors()
+	if err != nil {
+		t.Fatalf("failed to type-check packages: %v", err)
+	}
+	report, err := applyFixes(found, priority, func() error {
+		after, err := typeErrors()
+		if err != nil {
+			return err
+		}
+		return newTypeError(before, after)
+	})
+	if err != nil {
+		t.Fatalf("failed to apply fixes: %v", err)
+	}
+	var n int
+	for i, f := range found {
+		if report.fixed[i] {
+			n++
+		} else if err, ok := report.broken[i]; ok {
+			t.Logf("rolled back fix %q for %s: [%s] %s: %v",
+				f.SuggestedFixes[0].Message, f.posn, f.analyzer, f.Message,
+				err,
+			)
+		} else if j, ok := report.skipped[i]; ok {
+			t.Logf("skipped fix %q for %s: [%s] %s: "+
+				"conflicts with fix %q from %s",
+				f.SuggestedFixes[0].Message, f.posn, f.analyzer, f.Message,
+				found[j].SuggestedFixes[0].Message, found[j].analyzer,
+			)
+		}
+	}
+	if n > 0 {
+		t.Logf("applied %d fixes to %d files", n, len(report.changed))
+	}
+	return n
+}
+
+// typeErrors loads and type-checks the current package and its tests, and
+// returns their errors.
+func typeErrors() ([]packages.Error, error) {
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes |
+			packages.NeedSyntax | packages.NeedTypesInfo,
+		Tests: true,
+	}, ".")
+	if err != nil {
+		return nil, err
+	}
+	var errs []packages.Error
+	for _, pkg := range pkgs {
+		errs = append(errs, pkg.Errors...)
+	}
+	return errs, nil
+}
+
+// newTypeError returns the first error in after whose message is not in
+// before, counting repeats, or nil if there is none.
+func newTypeError(before, after []packages.Error) error {
+	old := make(map[string]int)
+	for _, e := range before {
+		old[e.Msg]++
+	}
+	for _, e := range after {
+		if old[e.Msg] == 0 {
+			return e
+		}
+		old[e.Msg]--
+	}
+	return nil
+}
+
+// An edit is a [analysis.TextEdit] resolved to byte offsets in a file.
+type edit struct {
+	start, end int
+	new        string
+}
+
+// conflicts reports whether e and o cannot both be applied. Identical edits
+// do not conflict, but overlapping ones do, as do distinct insertions at the
+// same offset, whose order would be arbitrary.
+func (e edit) conflicts(o edit) bool {
+	if e == o {
+		return false
+	}
+	return e.start == o.start || (e.start < o.end && o.start < e.end)
+}
+
+// fileEdits holds edits by file name.
+type fileEdits map[string][]edit
+
+// resolveFix returns the edits of fix by file name.
+func resolveFix(fset *token.FileSet, fix analysis.SuggestedFix) (fileEdits, error) {
+	edits := make(fileEdits)
+	for _, te := range fix.TextEdits {
+		start := fset.Position(te.Pos)
+		end := start
+		if te.End.IsValid() {
+			end = fset.Position(te.End)
+		}
+		if !start.IsValid() || start.Filename != end.Filename ||
+			end.Offset < start.Offset {
+			return nil, fmt.Errorf("bad edit in fix %q", fix.Message)
+		}
+		edits[start.Filename] = append(edits[start.Filename],
+			edit{start.Offset, end.Offset, string(te.NewText)},
+		)
+	}
+	for _, es := range edits {
+		for i, e := range es {
+			for _, o := range es[i+1:] {
+				if e.conflicts(o) {
+					return nil, fmt.Errorf("fix %q has overlapping edits",
+						fix.Message,
+					)
+				}
+			}
+		}
+	}
+	return edits, nil
+}
+
+// add adds edits to f.
+func (f fileEdits) add(edits fileEdits) {
+	for name, es := range edits {
+		f[name] = append(f[name], es...)
+	}
+}
+
+// conflicts reports whether any of edits conflicts with those in f.
+func (f fileEdits) conflicts(edits fileEdits) bool {
+	for name, es := range edits {
+		for _, e := range es {
+			for _, o := range f[name] {
+				if e.conflicts(o) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// applyEdits returns src with edits, which must not conflict, applied.
+func applyEdits(src []byte, edits []edit) ([]byte, error) {
+	edits = slices.Clone(edits)
+	slices.SortFunc(edits, func(a, b edit) int {
+		return cmp.Or(cmp.Compare(a.start, b.start), cmp.Compare(a.end, b.end))
+	})
+	edits = slices.Compact(edits)
+	var b strings.Builder
+	last := 0
+	for _, e := range edits {
+		if e.start < last || e.end > len(src) {
+			return nil, fmt.Errorf("edit [%d, %d) out of range", e.start, e.end)
+		}
+		b.Write(src[last:e.start])
+		b.WriteString(e.new)
+		last = e.end
+	}
+	b.Write(src[last:])
+	return []byte(b.String()), nil
+}
+
+// A fixReport describes the outcome of applying the fixes of findings.
+type fixReport struct {
+	fixed   []bool        // Whether the fix of each finding was applied.
+	broken  map[int]error // How the fixes that were rolled back broke.
+	skipped map[int]int   // The fix that won over each skipped fix.
+	changed []string      // The files changed, sorted.
+}
+
+// applyFixes applies the first suggested fix of each finding and formats the
+// files it changes.
+//
+// Fixes whose edits conflict are resolved in favor of the analyzer that
+// comes first in priority, then of the finding that comes first in found;
+// analyzers not in priority come last. The others are skipped.
+//
+// If check fails once the fixes are applied, each fix is instead applied in
+// turn and rolled back if check fails after it, so that the fixes applied
+// leave the code as sound as check found it.
+func applyFixes(found []finding, priority []string, check func() error) (*fixReport, error) {
+	report := &fixReport{
+		fixed:   make([]bool, len(found)),
+		broken:  make(map[int]error),
+		skipped: make(map[int]int),
+	}
+	rank := func(analyzer string) int {
+		if i := slices.Index(priority, analyzer); i >= 0 {
+			return i
+		}
+		return len(priority)
+	}
+	var order []int
+	for i, f := range found {
+		if len(f.SuggestedFixes) > 0 {
+			order = append(order, i)
+		}
+	}
+	slices.SortStableFunc(order, func(i, j int) int {
+		return cmp.Compare(rank(found[i].analyzer), rank(found[j].analyzer))
+	})
+	var (
+		accepted []int
+		edits    = make(map[int]fileEdits)
+		all      = make(fileEdits)
+	)
+candidates:
+	for _, i := range order {
+		e, err := resolveFix(found[i].pkg.Fset, found[i].SuggestedFixes[0])
+		if err != nil {
+			continue
+		}
+		for _, j := range accepted {
+			if edits[j].conflicts(e) {
+				report.skipped[i] = j
+				continue candidates
+			}
+		}
+		all.add(e)
+		edits[i] = e
+		accepted = append(accepted, i)
+	}
+	if len(accepted) == 0 {
+		return report, nil
+	}
+	slices.Sort(accepted)
+
+	orig := make(map[string][]byte)
+	for _, name := range sortedKeys(all) {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		orig[name] = src
+	}
+	// write rewrites the named files, or every file if names is nil, with
+	// the fixes of the findings in applied.
+	write := func(applied []int, names []string) error {
+		union := make(fileEdits)
+		for _, i := range applied {
+			union.add(edits[i])
+		}
+		if names == nil {
+			names = sortedKeys(orig)
+		}
+		for _, name := range names {
+			out, err := applyEdits(orig[name], union[name])
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if len(union[name]) > 0 {
+				if formatted, err := format.Source(out); err == nil {
+					out = formatted
+				}
+			}
+			if err := os.WriteFile(name, out, 0o644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := write(accepted, nil); err != nil {
+		return nil, err
+	}
+	applied := accepted
+	if err := check(); err != nil {
+		if err := write(nil, nil); err != nil {
+			return nil, err
+		}
+		applied = nil
+		for _, i := range accepted {
+			names := sortedKeys(edits[i])
+			if err := write(append(applied, i), names); err != nil {
+				return nil, err
+			}
+			if err := check(); err != nil {
+				report.broken[i] = err
+				if err := write(applied, names); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			applied = append(applied, i)
+		}
+	}
+	changed := make(fileEdits)
+	for _, i := range applied {
+		report.fixed[i] = true
+		changed.add(edits[i])
+	}
+	report.changed = sortedKeys(changed)
+	return report, nil
+}
+
+// fixDiff returns a unified diff of the changes fix makes to each file, in
+// order of file name.
+func fixDiff(fset *token.FileSet, fix analysis.SuggestedFix) (string, error) {
+	edits, err := resolveFix(fset, fix)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, name := range sortedKeys(edits) {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		for _, te := range fix.TextEdits {
+			tf := fset.File(te.Pos)
+			if tf.Name() == name && tf.Size() != len(src) {
+				return "", fmt.Errorf("%s has changed", name)
+			}
+		}
+		out, err := applyEdits(src, edits[name])
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		b.WriteString(unifiedDiff(name, string(src), string(out)))
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,137 @@
+package checker
+
+import (
+	"encoding/json"
+	"go/token"
+	"os"
+	"path/filepath"
+)
+
+// jsonReportEnv is the environment variable from which [Run] takes
+// Config.JSONReport, if it is not set.
+const jsonReportEnv = "CHECKER_JSON_REPORT"
+
+// A jsonReport is the report written to Config.JSONReport.
+type jsonReport struct {
+	Diagnostics []jsonDiagnostic `json:"diagnostics"`
+	Errors      []string         `json:"errors"`
+}
+
+type jsonDiagnostic struct {
+	Analyzer       string        `json:"analyzer"`
+	Severity       string        `json:"severity"`
+	Package        string        `json:"package"`
+	Range          jsonRange     `json:"range"`
+	Message        string        `json:"message"`
+	Category       string        `json:"category,omitempty"`
+	URL            string        `json:"url,omitempty"`
+	Related        []jsonRelated `json:"related,omitempty"`
+	SuggestedFixes []jsonFix     `json:"suggested_fixes,omitempty"`
+}
+
+// A jsonRange is a range of source text. Its file name is slash-separated
+// and relative to the module root, if the file is in the module.
+type jsonRange struct {
+	File  string       `json:"file"`
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+// A jsonPosition is a position in a file. Its line and column count from
+// 1, and its column and offset count bytes.
+type jsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+type jsonRelated struct {
+	Range   jsonRange `json:"range"`
+	Message string    `json:"message"`
+}
+
+type jsonFix struct {
+	Message string     `json:"message"`
+	Edits   []jsonEdit `json:"edits"`
+}
+
+type jsonEdit struct {
+	Range   jsonRange `json:"range"`
+	NewText string    `json:"new_text"`
+}
+
+// newJSONReport returns a report of found and errs, with file names relative
+// to root.
+func newJSONReport(root string, found []finding, errs []error) jsonReport {
+	report := jsonReport{
+		Diagnostics: []jsonDiagnostic{},
+		Errors:      []string{},
+	}
+	for _, err := range errs {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	for _, f := range found {
+		fset := f.pkg.Fset
+		d := jsonDiagnostic{
+			Analyzer: f.analyzer,
+			Severity: f.severity.String(),
+			Package:  f.pkg.PkgPath,
+			Range:    newJSONRange(root, fset, f.Pos, f.End),
+			Message:  f.Message,
+			Category: f.Category,
+			URL:      f.URL,
+		}
+		for _, rel := range f.Related {
+			d.Related = append(d.Related, jsonRelated{
+				Range:   newJSONRange(root, fset, rel.Pos, rel.End),
+				Message: rel.Message,
+			})
+		}
+		for _, fix := range f.SuggestedFixes {
+			jf := jsonFix{Message: fix.Message, Edits: []jsonEdit{}}
+			for _, e := range fix.TextEdits {
+				jf.Edits = append(jf.Edits, jsonEdit{
+					Range:   newJSONRange(root, fset, e.Pos, e.End),
+					NewText: string(e.NewText),
+				})
+			}
+			d.SuggestedFixes = append(d.SuggestedFixes, jf)
+		}
+		report.Diagnostics = append(report.Diagnostics, d)
+	}
+	return report
+}
+
+// newJSONRange returns the range from pos to end, or an empty range at pos if
+// end is not valid.
+func newJSONRange(root string, fset *token.FileSet, pos, end token.Pos) jsonRange {
+	start := fset.Position(pos)
+	stop := start
+	if end.IsValid() {
+		stop = fset.Position(end)
+	}
+	return jsonRange{
+		File:  moduleRelative(root, start.Filename),
+		Start: jsonPosition{start.Line, start.Column, start.Offset},
+		End:   jsonPosition{stop.Line, stop.Column, stop.Offset},
+	}
+}
+
+// moduleRelative returns the slash-separated name of a file relative to
+// root, or its name unchanged if it is not below root.
+func moduleRelative(root, name string) string {
+	rel, err := filepath.Rel(root, name)
+	if err != nil || !filepath.IsLocal(rel) {
+		return name
+	}
+	return filepath.ToSlash(rel)
+}
+
+// writeJSONReport writes a report of found and errs to the named file.
+func writeJSONReport(name, root string, found []finding, errs []error) error {
+	buf, err := json.MarshalIndent(newJSONReport(root, found, errs), "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(buf, '\n'), 0o644)
+}
@@ -0,0 +1,119 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// unknownNames reports the analyzer names in r that are not in known,
+// suggesting the closest known name where there is a plausible one. It also
+// reports glob patterns that match no known analyzer, and directives whose
+// exclusions leave nothing for them to match.
+//
+// Only //ignore directives are checked. Foreign directives routinely name
+// linters that checker does not run.
+func unknownNames(r ignoreRange, known map[string]struct{}) (diags []analysis.Diagnostic) {
+	if !strings.HasPrefix(r.kind, "//ignore") {
+		return nil
+	}
+	report := func(format string, args ...any) {
+		diags = append(diags, analysis.Diagnostic{
+			Pos:      r.pos,
+			Category: name,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+	seen := make(map[string]struct{})
+	for _, s := range r.selectors {
+		if _, ok := seen[s.analyzer]; ok || s.analyzer == "all" {
+			continue
+		}
+		seen[s.analyzer] = struct{}{}
+		if isGlob(s.analyzer) {
+			if !s.matchesAny(known) {
+				report("pattern %q in %s directive matches no analyzer",
+					s.analyzer, r.kind,
+				)
+			}
+			continue
+		}
+		if _, ok := known[s.analyzer]; ok {
+			continue
+		}
+		msg := fmt.Sprintf("unknown analyzer %q in %s directive",
+			s.analyzer, r.kind,
+		)
+		if c := closest(s.analyzer, known); c != "" {
+			msg += fmt.Sprintf("; did you mean %q?", c)
+		}
+		report("%s", msg)
+	}
+	if diags == nil && !canMatch(r.selectors, known) {
+		report("%s directive can never match: "+
+			"every analyzer it includes is excluded", r.kind,
+		)
+	}
+	return
+}
+
+// canMatch reports whether selectors include some known analyzer without
+// excluding all of its diagnostics.
+func canMatch(selectors []selector, known map[string]struct{}) bool {
+	for k := range known {
+		var included, excluded bool
+		for _, s := range selectors {
+			if !s.matchesName(k) {
+				continue
+			}
+			if !s.exclude {
+				included = true
+			} else if s.category == "" && s.message == nil {
+				excluded = true
+			}
+		}
+		if included && !excluded {
+			return true
+		}
+	}
+	return false
+}
+
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// closest returns the name in known nearest to s by edit distance, or
+// the empty string if none is near enough to be a likely typo.
+func closest(s string, known map[string]struct{}) (best string) {
+	bestDist := max(1, len(s)/3) + 1
+	for k := range known {
+		d := editDistance(s, k)
+		if d < bestDist || d == bestDist && k < best {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,144 @@
+package checker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// counts holds the number of diagnostics by package path and analyzer.
+type counts map[string]map[string]int
+
+// countFindings counts found by package path and analyzer.
+func countFindings(found []finding) counts {
+	c := make(counts)
+	for _, f := range found {
+		c.add(f.pkg.PkgPath, f.analyzer, 1)
+	}
+	return c
+}
+
+func (c counts) add(pkg, analyzer string, n int) {
+	if c[pkg] == nil {
+		c[pkg] = make(map[string]int)
+	}
+	c[pkg][analyzer] += n
+}
+
+// A budgetChange is a package and analyzer whose count differs from its
+// budget in a ratchet file.
+type budgetChange struct {
+	pkg, analyzer string
+	count, budget int
+}
+
+func (b budgetChange) String() string {
+	return fmt.Sprintf("%s: %s: %d diagnostics, budget %d",
+		b.pkg, b.analyzer, b.count, b.budget,
+	)
+}
+
+// compareCounts compares the current counts against budgets. It returns the
+// packages and analyzers whose count went up, and those whose count went
+// down, each sorted.
+func compareCounts(current, budgets counts) (over, under []budgetChange) {
+	for _, pkg := range sortedKeys(current, budgets) {
+		for _, a := range sortedKeys(current[pkg], budgets[pkg]) {
+			b := budgetChange{pkg, a, current[pkg][a], budgets[pkg][a]}
+			switch {
+			case b.count > b.budget:
+				over = append(over, b)
+			case b.count < b.budget:
+				under = append(under, b)
+			}
+		}
+	}
+	return over, under
+}
+
+// sortedKeys returns the keys of maps, sorted and without duplicates.
+func sortedKeys[V any](ms ...map[string]V) []string {
+	var keys []string
+	for _, m := range ms {
+		keys = slices.AppendSeq(keys, maps.Keys(m))
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// readRatchet reads the ratchet file at name. A missing file has no budget
+// for anything.
+func readRatchet(name string) (counts, error) {
+	buf, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return counts{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var c counts
+	if err := json.Unmarshal(buf, &c); err != nil {
+		return nil, fmt.Errorf("bad ratchet %s: %w", name, err)
+	}
+	return c, nil
+}
+
+// writeRatchet writes c to the ratchet file at name.
+func writeRatchet(name string, c counts) error {
+	buf, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(buf, '\n'), 0o644)
+}
+
+// checkRatchet returns the findings of each package and analyzer whose count
+// exceeds its budget in c.Ratchet, and logs those whose count fell below it.
+// If c.UpdateRatchet is set, it records the counts of found in c.Ratchet
+// instead and returns nothing.
+func (c Config) checkRatchet(t testingT, found []finding) []finding {
+	t.Helper()
+	current := countFindings(found)
+	if c.UpdateRatchet {
+		if err := writeRatchet(c.Ratchet, current); err != nil {
+			t.Fatalf("failed to write ratchet: %v", err)
+		}
+		t.Logf("recorded diagnostic counts in %s", c.Ratchet)
+		return nil
+	}
+	budgets, err := readRatchet(c.Ratchet)
+	if err != nil {
+		t.Fatalf("failed to read ratchet: %v", err)
+	}
+	over, under := compareCounts(current, budgets)
+	if len(under) > 0 {
+		var b strings.Builder
+		for _, u := range under {
+			fmt.Fprintln(&b, u)
+		}
+		t.Logf("diagnostic counts went down; "+
+			"set UpdateRatchet to rewrite %s\n%s", c.Ratchet, b.String(),
+		)
+	}
+	if len(over) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	exceeded := make(counts)
+	for _, o := range over {
+		fmt.Fprintln(&b, o)
+		exceeded.add(o.pkg, o.analyzer, 1)
+	}
+	t.Errorf("diagnostic counts exceed %s\n%s", c.Ratchet, b.String())
+	var fresh []finding
+	for _, f := range found {
+		if exceeded[f.pkg.PkgPath][f.analyzer] > 0 {
+			fresh = append(fresh, f)
+		}
+	}
+	return fresh
+}
@@ -0,0 +1,228 @@
+package checker
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	gochecker "golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+)
+
+// A finding is a diagnostic reported in a root package, along with the
+// analyzer that produced it and its severity.
+type finding struct {
+	analyzer string
+	severity severity
+	pkg      *packages.Package
+	posn     token.Position
+	end      token.Position
+	analysis.Diagnostic
+
+	suppressions []suppression // How the diagnostic was suppressed, if it was.
+}
+
+// findings returns the diagnostics of the root actions of graph, and those
+// that were suppressed, sorted by position, along with the errors of every
+// action. Diagnostics in files that belong to several packages, such as foo
+// and foo.test, are reported once. A directive in such a file is reported as
+// unused only if it is unused in every package that has the file.
+func findings(graph *gochecker.Graph) (found, suppressed []finding, errs []error) {
+	type key struct {
+		posn, end token.Position
+		analyzer  string
+		message   string
+	}
+	seen := make(map[key]bool)
+	add := func(list *[]finding, pkg *packages.Package, d reported, by []suppression) {
+		f := finding{
+			analyzer:     d.analyzer,
+			severity:     d.severity,
+			pkg:          pkg,
+			posn:         pkg.Fset.Position(d.Pos),
+			end:          pkg.Fset.Position(d.End),
+			Diagnostic:   d.Diagnostic,
+			suppressions: by,
+		}
+		k := key{f.posn, f.end, f.analyzer, f.Message}
+		if seen[k] {
+			return
+		}
+		seen[k] = true
+		*list = append(*list, f)
+	}
+	type candidate struct {
+		pkg    *packages.Package
+		d      reported
+		unused int // Packages in which the directive is unused.
+	}
+	var (
+		candidates = make(map[token.Position]*candidate)
+		packagesOf = make(map[string]int) // Packages that have each file.
+	)
+	for act := range graph.All() {
+		if act.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", act.Analyzer.Name, act.Err))
+			continue
+		}
+		if !act.IsRoot {
+			continue
+		}
+		res, _ := act.Result.(*result)
+		if res == nil {
+			continue
+		}
+		for _, d := range res.diags {
+			add(&found, act.Package, d, nil)
+		}
+		for _, d := range res.suppressed {
+			add(&suppressed, act.Package, d.reported, d.by)
+		}
+		for _, file := range act.Package.Syntax {
+			packagesOf[act.Package.Fset.File(file.Pos()).Name()]++
+		}
+		for _, d := range res.unused {
+			posn := act.Package.Fset.PositionFor(d.Pos, false)
+			c, ok := candidates[posn]
+			if !ok {
+				c = &candidate{pkg: act.Package, d: d}
+				candidates[posn] = c
+			}
+			c.unused++
+		}
+	}
+	for posn, c := range candidates {
+		if c.unused == packagesOf[posn.Filename] {
+			add(&found, c.pkg, c.d, nil)
+		}
+	}
+	sortFindings(found)
+	sortFindings(suppressed)
+	return found, suppressed, errs
+}
+
+// sortFindings sorts found by position, then by analyzer and message.
+func sortFindings(found []finding) {
+	slices.SortStableFunc(found, func(a, b finding) int {
+		return cmp.Or(
+			cmp.Compare(a.posn.Filename, b.posn.Filename),
+			cmp.Compare(a.posn.Offset, b.posn.Offset),
+			cmp.Compare(a.analyzer, b.analyzer),
+			cmp.Compare(a.Message, b.Message),
+		)
+	})
+}
+
+// printFindings writes each finding to w, followed by the source lines it
+// spans, in the form printed by go vet, and a diff of each suggested fix.
+func printFindings(w *bytes.Buffer, found []finding) {
+	src := make(sources)
+	print := func(fset *token.FileSet, pos, end token.Pos, message string) {
+		posn := fset.Position(pos)
+		fmt.Fprintf(w, "%s: %s\n", posn, message)
+		endPosn := fset.Position(end)
+		if !endPosn.IsValid() {
+			endPosn = posn
+		}
+		for i := posn.Line; i <= endPosn.Line; i++ {
+			if line, ok := src.line(posn.Filename, i); ok {
+				fmt.Fprintf(w, "%d\t%s\n", i, line)
+			}
+		}
+	}
+	for _, f := range found {
+		print(f.pkg.Fset, f.Pos, f.End, f.Message)
+		for _, rel := range f.Related {
+			print(f.pkg.Fset, rel.Pos, rel.End, "\t"+rel.Message)
+		}
+		printFixes(w, f)
+	}
+}
+
+// printFixes writes a diff of each suggested fix of f to w.
+func printFixes(w *bytes.Buffer, f finding) {
+	for _, fix := range f.SuggestedFixes {
+		fmt.Fprintf(w, "\tsuggested fix: %s\n", fix.Message)
+		diff, err := fixDiff(f.pkg.Fset, fix)
+		if err != nil {
+			fmt.Fprintf(w, "\t(cannot show diff: %v)\n", err)
+			continue
+		}
+		for line := range strings.Lines(diff) {
+			fmt.Fprintf(w, "\t%s", line)
+		}
+	}
+}
+
+// printError writes f to w in the form of a compiler error, which editors
+// and IDEs link to the source, followed by its line with a caret under its
+// column, its related information, and a diff of each suggested fix.
+func printError(w *bytes.Buffer, src sources, f finding) {
+	var level string
+	if f.severity == severityWarning {
+		level = "warning: "
+	}
+	fmt.Fprintf(w, "%s: %s[%s] %s\n",
+		relPosition(f.posn), level, f.analyzer, f.Message,
+	)
+	if line, ok := src.line(f.posn.Filename, f.posn.Line); ok {
+		fmt.Fprintf(w, "\t%s\n\t%s^\n", line, indent(line, f.posn.Column))
+	}
+	for _, rel := range f.Related {
+		posn := f.pkg.Fset.Position(rel.Pos)
+		fmt.Fprintf(w, "\t%s: %s\n", relPosition(posn), rel.Message)
+	}
+	printFixes(w, f)
+}
+
+// relPosition formats posn with its file name relative to the current
+// directory, if the file is below it.
+func relPosition(posn token.Position) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, posn.Filename); err == nil &&
+			filepath.IsLocal(rel) {
+			posn.Filename = rel
+		}
+	}
+	return posn.String()
+}
+
+// indent returns the whitespace that lines up with column col, counting
+// bytes from 1, of line: a tab for each tab and a space for anything else.
+func indent(line string, col int) string {
+	if col < 1 {
+		return ""
+	}
+	var b strings.Builder
+	for _, r := range line[:min(col-1, len(line))] {
+		if r == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// sources caches the lines of source files by name.
+type sources map[string][]string
+
+// line returns line n, counting from 1, of the named file.
+func (s sources) line(filename string, n int) (string, bool) {
+	lines, ok := s[filename]
+	if !ok {
+		data, _ := os.ReadFile(filename)
+		lines = strings.Split(string(data), "\n")
+		s[filename] = lines
+	}
+	if n < 1 || n > len(lines) {
+		return "", false
+	}
+	return lines[n-1], true
+}
@@ -0,0 +1,170 @@
+package checker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// rulesFile is the name of the file, at the module root, from which [Run]
+// loads [Rule] values.
+const rulesFile = "checker.json"
+
+// A Rule suppresses diagnostics without a directive in the source. Rules are
+// given in [Config], or in a checker.json file at the module root:
+//
+//	{
+//	    "ignore": [
+//	        {"path": "internal/gen", "analyzer": "linelen"},
+//	        {"path": "cmd", "analyzer": "errcheck", "message": "fmt\\.Fprint"}
+//	    ]
+//	}
+type Rule struct {
+	// Path is a slash-separated glob pattern, relative to the root of the
+	// module containing a file, that matches the file or any directory
+	// above it. An empty Path matches every file.
+	Path string `json:"path,omitempty"`
+
+	// Analyzer is the name of an analyzer, a glob pattern, a group from
+	// Config.Groups, or "all". A dot and a category may follow the name.
+	Analyzer string `json:"analyzer"`
+
+	// Message is a regular expression that diagnostic messages must match.
+	// An empty Message matches every message.
+	Message string `json:"message,omitempty"`
+}
+
+// loadRules returns the rules in the checker.json file in dir, if there is
+// one.
+func loadRules(dir string) ([]Rule, error) {
+	buf, err := os.ReadFile(filepath.Join(dir, rulesFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Ignore []Rule `json:"ignore"`
+	}
+	if err := json.Unmarshal(buf, &file); err != nil {
+		return nil, fmt.Errorf("bad %s: %w", rulesFile, err)
+	}
+	return file.Ignore, nil
+}
+
+// moduleRoot returns the nearest directory at or above dir that contains a
+// go.mod file.
+func moduleRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no go.mod found")
+		}
+		dir = parent
+	}
+}
+
+// A fileRule is a compiled [Rule].
+type fileRule struct {
+	path string
+	directive
+}
+
+// compileRules compiles c.Ignore to directives that apply to whole files.
+func (c Config) compileRules() ([]fileRule, error) {
+	rules := make([]fileRule, len(c.Ignore))
+	for i, r := range c.Ignore {
+		if _, err := path.Match(r.Path, ""); err != nil {
+			return nil, fmt.Errorf("bad path pattern %q in rule: %w",
+				r.Path, err,
+			)
+		}
+		name, category, _ := strings.Cut(r.Analyzer, ".")
+		if name == "" {
+			return nil, fmt.Errorf("rule for path %q names no analyzer",
+				r.Path,
+			)
+		}
+		if _, err := path.Match(name, ""); err != nil {
+			return nil, fmt.Errorf("bad analyzer pattern %q in rule: %w",
+				name, err,
+			)
+		}
+		sel := selector{analyzer: name, category: category}
+		if r.Message != "" {
+			var err error
+			if sel.message, err = regexp.Compile(r.Message); err != nil {
+				return nil, fmt.Errorf("bad message pattern in rule: %w", err)
+			}
+		}
+		rules[i] = fileRule{r.Path, directive{
+			kind:      rulesFile,
+			selectors: c.expandGroups([]selector{sel}),
+			file:      true,
+		}}
+	}
+	return rules, nil
+}
+
+// ruleRanges returns a range covering each file of the package for every
+// rule whose path matches it.
+func ruleRanges(pass *analysis.Pass, rules []fileRule) (ranges []ignoreRange) {
+	if len(rules) == 0 {
+		return nil
+	}
+	roots := make(map[string]string)
+	for _, file := range pass.Files {
+		filename := pass.Fset.File(file.Pos()).Name()
+		dir := filepath.Dir(filename)
+		root, ok := roots[dir]
+		if !ok {
+			root, _ = moduleRoot(dir)
+			roots[dir] = root
+		}
+		if root == "" {
+			continue
+		}
+		rel, err := filepath.Rel(root, filename)
+		if err != nil {
+			continue
+		}
+		for _, r := range rules {
+			if matchPath(r.path, filepath.ToSlash(rel)) {
+				ranges = append(ranges, ignoreRange{
+					start:     file.FileStart,
+					end:       file.End(),
+					directive: r.directive,
+				})
+			}
+		}
+	}
+	return
+}
+
+// matchPath reports whether pattern matches name or any directory above it.
+func matchPath(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+	for ; name != "." && name != "/"; name = path.Dir(name) {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,325 @@
+package checker
+
+import (
+	"encoding/json"
+	"go/token"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// sarifEnv is the environment variable from which [Run] takes Config.SARIF,
+// if it is not set.
+const sarifEnv = "CHECKER_SARIF"
+
+// srcRoot is the base URI identifier under which SARIF file locations are
+// relative to the module root.
+const srcRoot = "%SRCROOT%"
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds"`
+	Results            []sarifResult                    `json:"results"`
+	Invocations        []sarifInvocation                `json:"invocations"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string        `json:"id"`
+	ShortDescription *sarifMessage `json:"shortDescription,omitempty"`
+	FullDescription  *sarifMessage `json:"fullDescription,omitempty"`
+	HelpURI          string        `json:"helpUri,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string             `json:"ruleId"`
+	RuleIndex        int                `json:"ruleIndex"`
+	Level            string             `json:"level"`
+	Message          sarifMessage       `json:"message"`
+	Locations        []sarifLocation    `json:"locations"`
+	RelatedLocations []sarifLocation    `json:"relatedLocations,omitempty"`
+	Fixes            []sarifFix         `json:"fixes,omitempty"`
+	Suppressions     []sarifSuppression `json:"suppressions,omitempty"`
+	Properties       map[string]string  `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               int                   `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// A sarifRegion is a region of a text file. Its lines and columns count
+// from 1, and its columns count UTF-16 code units, as SARIF requires by
+// default.
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion  `json:"deletedRegion"`
+	InsertedContent sarifMessage `json:"insertedContent"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+// newSARIFLog returns a SARIF log with one run, which has a rule for each of
+// analyzers and a result for each diagnostic, found or suppressed. Files are
+// located relative to root.
+func newSARIFLog(root string, analyzers []*analysis.Analyzer, found, suppressed []finding, errs []error) sarifLog {
+	w := sarifWriter{root: root, src: make(sources)}
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "lesiw.io/checker",
+			InformationURI: "https://lesiw.io/checker",
+			Rules:          []sarifRule{},
+		}},
+		OriginalURIBaseIDs: map[string]sarifArtifactLocation{
+			srcRoot: {URI: fileURI(root) + "/"},
+		},
+		Results: []sarifResult{},
+		Invocations: []sarifInvocation{{
+			ExecutionSuccessful: len(errs) == 0,
+		}},
+	}
+	rules := make(map[string]int)
+	addRule := func(a *analysis.Analyzer) {
+		if _, ok := rules[a.Name]; ok {
+			return
+		}
+		rules[a.Name] = len(run.Tool.Driver.Rules)
+		rule := sarifRule{ID: a.Name, HelpURI: a.URL}
+		if a.Doc != "" {
+			short, _, _ := strings.Cut(a.Doc, "\n\n")
+			rule.ShortDescription = &sarifMessage{short}
+			rule.FullDescription = &sarifMessage{a.Doc}
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+	addRule(&analysis.Analyzer{Name: name, Doc: doc})
+	visitAnalyzers(analyzers, addRule)
+
+	for _, f := range slices.Concat(found, suppressed) {
+		if _, ok := rules[f.analyzer]; !ok {
+			addRule(&analysis.Analyzer{Name: f.analyzer})
+		}
+		run.Results = append(run.Results, w.result(f, rules[f.analyzer]))
+	}
+	for _, err := range errs {
+		inv := &run.Invocations[0]
+		inv.ToolExecutionNotifications = append(inv.ToolExecutionNotifications,
+			sarifNotification{Level: "error", Message: sarifMessage{err.Error()}},
+		)
+	}
+	return sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}
+}
+
+// visitAnalyzers calls visit for each of analyzers and their transitive
+// requirements, once each, in depth-first order.
+func visitAnalyzers(analyzers []*analysis.Analyzer, visit func(*analysis.Analyzer)) {
+	seen := make(map[*analysis.Analyzer]bool)
+	var walk func([]*analysis.Analyzer)
+	walk = func(analyzers []*analysis.Analyzer) {
+		for _, a := range analyzers {
+			if seen[a] {
+				continue
+			}
+			seen[a] = true
+			visit(a)
+			walk(a.Requires)
+		}
+	}
+	walk(analyzers)
+}
+
+// A sarifWriter converts findings to SARIF results.
+type sarifWriter struct {
+	root string
+	src  sources
+}
+
+func (w sarifWriter) result(f finding, rule int) sarifResult {
+	fset := f.pkg.Fset
+	r := sarifResult{
+		RuleID:    f.analyzer,
+		RuleIndex: rule,
+		Level:     f.severity.String(),
+		Message:   sarifMessage{f.Message},
+		Locations: []sarifLocation{{
+			PhysicalLocation: w.physicalLocation(fset, f.Pos, f.End),
+		}},
+	}
+	if f.Category != "" {
+		r.Properties = map[string]string{"category": f.Category}
+	}
+	for i, rel := range f.Related {
+		r.RelatedLocations = append(r.RelatedLocations, sarifLocation{
+			ID:               i + 1,
+			PhysicalLocation: w.physicalLocation(fset, rel.Pos, rel.End),
+			Message:          &sarifMessage{rel.Message},
+		})
+	}
+	for _, fix := range f.SuggestedFixes {
+		sf := sarifFix{
+			Description:     sarifMessage{fix.Message},
+			ArtifactChanges: []sarifArtifactChange{},
+		}
+		changes := make(map[string]int)
+		for _, e := range fix.TextEdits {
+			loc := w.physicalLocation(fset, e.Pos, e.End)
+			i, ok := changes[loc.ArtifactLocation.URI]
+			if !ok {
+				i = len(sf.ArtifactChanges)
+				changes[loc.ArtifactLocation.URI] = i
+				sf.ArtifactChanges = append(sf.ArtifactChanges,
+					sarifArtifactChange{ArtifactLocation: loc.ArtifactLocation},
+				)
+			}
+			sf.ArtifactChanges[i].Replacements = append(
+				sf.ArtifactChanges[i].Replacements, sarifReplacement{
+					DeletedRegion:   loc.Region,
+					InsertedContent: sarifMessage{string(e.NewText)},
+				},
+			)
+		}
+		r.Fixes = append(r.Fixes, sf)
+	}
+	for _, s := range f.suppressions {
+		r.Suppressions = append(r.Suppressions, sarifSuppressionOf(s))
+	}
+	return r
+}
+
+// sarifSuppressionOf returns the SARIF form of s. Directives in the source
+// are suppressions in source; rules and generated files are external.
+func sarifSuppressionOf(s suppression) sarifSuppression {
+	switch s.kind {
+	case "":
+		return sarifSuppression{Kind: "external", Justification: "generated file"}
+	case rulesFile:
+		return sarifSuppression{Kind: "external", Justification: s.reason}
+	default:
+		return sarifSuppression{Kind: "inSource", Justification: s.reason}
+	}
+}
+
+// physicalLocation returns the location of the range from pos to end, or of
+// an empty range at pos if end is not valid.
+func (w sarifWriter) physicalLocation(fset *token.FileSet, pos, end token.Pos) sarifPhysicalLocation {
+	start := fset.Position(pos)
+	stop := start
+	if end.IsValid() {
+		stop = fset.Position(end)
+	}
+	loc := sarifArtifactLocation{URI: fileURI(start.Filename)}
+	if rel := moduleRelative(w.root, start.Filename); rel != start.Filename {
+		loc = sarifArtifactLocation{URI: relativeURI(rel), URIBaseID: srcRoot}
+	}
+	return sarifPhysicalLocation{
+		ArtifactLocation: loc,
+		Region: sarifRegion{
+			StartLine:   start.Line,
+			StartColumn: w.column(start),
+			EndLine:     stop.Line,
+			EndColumn:   w.column(stop),
+		},
+	}
+}
+
+// column returns the column of posn in UTF-16 code units, counting from 1.
+func (w sarifWriter) column(posn token.Position) int {
+	line, ok := w.src.line(posn.Filename, posn.Line)
+	if !ok || posn.Column < 1 || posn.Column-1 > len(line) {
+		return posn.Column
+	}
+	return len(utf16.Encode([]rune(line[:posn.Column-1]))) + 1
+}
+
+// fileURI returns the file URI of the named file.
+func fileURI(name string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(name)}
+	if !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path // A Windows path, such as C:/x.
+	}
+	return u.String()
+}
+
+// relativeURI returns the relative URI reference of a slash-separated path.
+func relativeURI(path string) string {
+	return (&url.URL{Path: path}).String()
+}
+
+// writeSARIF writes a SARIF log of found, suppressed and errs to the named
+// file.
+func writeSARIF(name, root string, analyzers []*analysis.Analyzer, found, suppressed []finding, errs []error) error {
+	log := newSARIFLog(root, analyzers, found, suppressed, errs)
+	buf, err := json.MarshalIndent(log, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(buf, '\n'), 0o644)
+}
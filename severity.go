@@ -0,0 +1,27 @@
+package checker
+
+import "slices"
+
+// A severity is how [Run] treats a diagnostic: an error fails the test, and
+// a warning is only logged.
+type severity int
+
+const (
+	severityError severity = iota
+	severityWarning
+)
+
+func (s severity) String() string {
+	if s == severityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// severityOf returns the severity of the diagnostics of the named analyzer.
+func (c Config) severityOf(analyzer string) severity {
+	if slices.Contains(c.Warn, analyzer) {
+		return severityWarning
+	}
+	return severityError
+}
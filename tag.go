@@ -0,0 +1,40 @@
+package checker
+
+import "golang.org/x/tools/go/analysis"
+
+// A Tag selects how a forwarded diagnostic names the analyzer that produced
+// it.
+type Tag int
+
+const (
+	// TagBoth applies both TagCategory and TagMessage.
+	TagBoth Tag = iota
+
+	// TagCategory sets the category of a diagnostic to the analyzer name,
+	// followed by a dot and the original category if there was one, as in
+	// "errcheck" or "printf.format". This is the form in which directives
+	// such as //ignore:printf.format name categories.
+	TagCategory
+
+	// TagMessage prefixes the message of a diagnostic with the analyzer
+	// name in brackets, as in "[errcheck] error return value not checked".
+	TagMessage
+
+	// TagNone forwards diagnostics unchanged.
+	TagNone
+)
+
+// apply returns d, tagged as reported by the named analyzer.
+func (t Tag) apply(analyzer string, d analysis.Diagnostic) analysis.Diagnostic {
+	if t == TagBoth || t == TagCategory {
+		if d.Category == "" {
+			d.Category = analyzer
+		} else {
+			d.Category = analyzer + "." + d.Category
+		}
+	}
+	if t == TagBoth || t == TagMessage {
+		d.Message = "[" + analyzer + "] " + d.Message
+	}
+	return d
+}
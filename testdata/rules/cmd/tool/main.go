@@ -0,0 +1,9 @@
+package main
+
+func MainHelper() {}
+
+func Helper() {} // want "Helper is public"
+
+var count1 int // want "count1 has numbers"
+
+func main() {}
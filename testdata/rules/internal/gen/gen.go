@@ -0,0 +1,3 @@
+package gen
+
+var Table1 int // want "Table1 is public"
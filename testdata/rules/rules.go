@@ -0,0 +1,3 @@
+package rules
+
+func Public1() {}
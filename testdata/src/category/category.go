@@ -0,0 +1,34 @@
+package category
+
+//ignore:declkinds.func
+func suppressed() {}
+
+//ignore:declkinds.var // want "unused //ignore directive"
+func reported() {} // want "reported is a func"
+
+//ignore:declkinds.var,declkinds.const
+var (
+	a = 1
+	b = 2
+)
+
+//ignore:declkinds.var // want "unused //ignore directive"
+const c = 3 // want "c is a const"
+
+var d = 4 //ignore:declkinds.var
+
+//ignore:begin:declkinds.const
+const e = 5
+
+var f = 6 // want "f is a var"
+
+//ignore:end
+
+//ignore:declkinds.func/"^g "
+func g() {}
+
+//ignore:declkinds.const/"^g " // want "unused //ignore directive"
+const h = 7 // want "h is a const"
+
+//ignore:all.var
+var i = 8
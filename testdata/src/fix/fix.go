@@ -0,0 +1,8 @@
+package fix
+
+func Exported() {} // want "Exported is exported"
+
+//ignore:lowernames
+func Suppressed() {}
+
+func unexported() {}
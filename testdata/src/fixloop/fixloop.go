@@ -0,0 +1,8 @@
+package fixloop
+
+// Used keeps its name, since lowernames does not rename its uses.
+func Used() {}
+
+var _ = Used
+
+var item12 int
@@ -0,0 +1,31 @@
+package glob
+
+//ignore:all,-numberednames
+var Count1 int // want "Count1 has numbers"
+
+//ignore:all,-numberednames
+func PublicFunc() {}
+
+var Count2 int //ignore:*names
+
+//ignore:public*
+func AnotherPublic() {}
+
+var Count3 int //ignore:*names,-public* // want "Count3 is public"
+
+//ignore:all,-numberednames/"^count"
+var count4, item5 int // want "count4 has numbers"
+
+//ignore:style* // want `pattern "style\*" in //ignore directive matches no analyzer`
+func StylePublic() {} // want "StylePublic is public"
+
+func ExcludedPublic() {} //ignore:-publicnames // want "can never match" "ExcludedPublic is public"
+
+//ignore:public*,-publicnames // want "can never match"
+func ExcludedPublic2() {} // want "ExcludedPublic2 is public" "ExcludedPublic2 has numbers"
+
+//ignore:publicnames,-publicnames.deprecated
+func NarrowlyExcluded() {}
+
+//ignore:numbered[ // want "invalid //ignore directive: bad analyzer pattern"
+var count6 int // want "count6 has numbers"
@@ -0,0 +1,19 @@
+package group
+
+//ignore:names
+var Count1 int
+
+//ignore:naming
+func PublicFunc() {}
+
+//ignore:naming,-numberednames
+var Count2 int // want "Count2 has numbers"
+
+//ignore:all,-names // want "can never match"
+var Count3 int // want "Count3 is public" "Count3 has numbers"
+
+//ignore:names/"has numbers"
+var Count4 int // want "Count4 is public"
+
+//ignore:style // want "unknown analyzer \"style\""
+var Count5 int // want "Count5 is public" "Count5 has numbers"
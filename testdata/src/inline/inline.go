@@ -2,23 +2,23 @@ package inline
 
 func goodFunc() {}
 
-var normalVar int //ignore:numberednames
+var normalVar int //ignore:numberednames // want "unused //ignore directive"
 
 func PublicFunc() {} //ignore:publicnames
 var count2 int    //ignore:numberednames
 var count3 int    // want "count3 has numbers"
 
-var anotherVar int //ignore:all
+var anotherVar int //ignore:all // want "unused //ignore directive"
 
 func AnotherPublic() {} //ignore:all
 var value3 int       //ignore:all
 
-var yetAnotherVar int //ignore:publicnames,numberednames
+var yetAnotherVar int //ignore:publicnames,numberednames // want "unused //ignore directive"
 
 func YetAnotherPublic() {} //ignore:publicnames,numberednames
 var item4 string        //ignore:publicnames,numberednames
 
-var commentedVar int //ignore:numberednames // This is legacy code
+var commentedVar int //ignore:numberednames // This is legacy code // want "unused //ignore directive"
 
 func CommentedPublic() {} //ignore:publicnames // Complex legacy function
 
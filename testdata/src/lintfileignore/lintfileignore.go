@@ -0,0 +1,7 @@
+package lintfileignore
+
+func PublicFunc() {}
+
+var count1 int // want "count1 has numbers"
+
+//lint:file-ignore publicnames transliterated from C
@@ -0,0 +1,13 @@
+package lintignore
+
+//lint:ignore publicnames exported for compatibility
+func PublicFunc() {}
+
+//lint:ignore publicnames,numberednames legacy names
+var Count1 int
+
+//lint:ignore numberednames nothing to suppress // want "unused //lint:ignore directive"
+func AnotherPublic() {} // want "AnotherPublic is public"
+
+//nolint:publicnames
+func NolintPublic() {} // want "NolintPublic is public"
@@ -0,0 +1,27 @@
+package message
+
+//ignore:publicnames/"^Public"
+func PublicFunc() {}
+
+//ignore:publicnames/"^Public" // want "unused //ignore directive"
+func ExportedFunc() {} // want "ExportedFunc is public"
+
+//ignore:publicnames/"^Public",numberednames
+var PublicCount1 int
+
+//ignore:publicnames/"^Public" // want "unused //ignore directive"
+var Count2 int // want "Count2 is public" "Count2 has numbers"
+
+//ignore:all/"has numbers"
+var Count3 int // want "Count3 is public"
+
+//ignore:all/"has numbers",publicnames/"^Public"
+var PublicCount4, Count5 int // want "Count5 is public"
+
+var count6 int //ignore:numberednames/"count6 has" -- generated name
+
+//ignore:publicnames/"(" // want "invalid //ignore directive: bad message pattern for publicnames"
+func BadPattern() {} // want "BadPattern is public"
+
+//ignore:publicnames/"is public, really\"\\b" // want "unused //ignore directive"
+func QuotedPattern() {} // want "QuotedPattern is public"
@@ -0,0 +1,24 @@
+package nolint
+
+//nolint:publicnames // exported for compatibility
+func PublicFunc() {}
+
+func InlinePublic() {} //nolint:publicnames
+
+//nolint:all
+var count1 int
+
+//nolint:publicnames,numberednames
+func Public2() {}
+
+//nolint:numberednames // want "unused //nolint directive"
+func AnotherPublic() {} // want "AnotherPublic is public"
+
+//ignore:publicnames
+func IgnoredPublic() {}
+
+// nolint:publicnames is not machine-readable.
+func SpacedPublic() {} // want "SpacedPublic is public"
+
+//lint:ignore publicnames not enabled
+func StaticPublic() {} // want "StaticPublic is public"
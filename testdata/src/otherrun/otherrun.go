@@ -0,0 +1,16 @@
+package otherrun
+
+// Only publicnames is run here. Another run in the same package covers
+// linelen, which is known but not run.
+
+//ignore:linelen
+func LongName() {} // want "LongName is public"
+
+//ignore:linelen,publicnames
+func PublicFunc() {}
+
+//ignore:linelenn // want `unknown analyzer "linelenn" in //ignore directive; did you mean "linelen"\?`
+func TypoFunc() {} // want "TypoFunc is public"
+
+//ignore:line*
+var lines int
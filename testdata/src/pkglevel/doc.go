@@ -0,0 +1,7 @@
+// Package pkglevel is transliterated from C.
+//
+//ignore:package:publicnames -- transliterated from C
+package pkglevel
+
+//ignore:package:numberednames // want "//ignore:package directive must precede the package clause"
+var count1 int // want "count1 has numbers"
@@ -0,0 +1,3 @@
+package pkglevel
+
+func AnotherPublic() {}
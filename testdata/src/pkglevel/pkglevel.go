@@ -0,0 +1,7 @@
+package pkglevel
+
+func PublicFunc() {}
+
+var Count2 int // want "Count2 has numbers"
+
+var x1 int // Note. //ignore:package:numberednames // want "must precede the package clause" "x1 has numbers"
@@ -0,0 +1,4 @@
+// Package pkgtests has public names only in its tests.
+//
+//ignore:package:publicnames -- run by the test runner
+package pkgtests
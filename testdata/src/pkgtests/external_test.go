@@ -0,0 +1,6 @@
+//ignore:package:publicnames -- run by the test runner
+package pkgtests_test
+
+import "testing"
+
+func TestExternal(t *testing.T) {}
@@ -0,0 +1,5 @@
+package pkgtests
+
+import "testing"
+
+func TestHelper(t *testing.T) { helper() }
@@ -0,0 +1,3 @@
+package pkgtests
+
+func helper() {}
@@ -0,0 +1,14 @@
+package reason
+
+//ignore:publicnames -- exported for compatibility
+func PublicFunc() {}
+
+//ignore:publicnames // want "//ignore directive has no reason"
+func AnotherPublic() {}
+
+//ignore:publicnames -- // want "//ignore directive has no reason"
+func EmptyReason() {}
+
+func InlinePublic() {} //ignore:publicnames -- part of the public API
+
+func UnjustifiedPublic() {} //ignore:publicnames // want "//ignore directive has no reason"
@@ -0,0 +1,7 @@
+package recorded
+
+func First() {}
+
+func Second() {}
+
+var item1 int
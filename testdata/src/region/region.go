@@ -0,0 +1,49 @@
+package region
+
+func _() {
+	var a1 int // want "a1 has numbers"
+	//ignore:begin:numberednames -- generated lookup table
+	var b1 int
+	var c2 int
+	//ignore:end
+	var d1 int // want "d1 has numbers"
+	_, _, _, _ = a1, b1, c2, d1
+}
+
+//ignore:begin
+func Exported1() {}
+
+func Exported2() {}
+
+//ignore:end
+
+func Exported3() {} // want "Exported3 is public" "Exported3 has numbers"
+
+//ignore:begin:publicnames
+//ignore:begin:numberednames
+var Nested1 int
+
+//ignore:end:numberednames
+var Nested2 int // want "Nested2 has numbers"
+
+//ignore:end:publicnames
+
+//ignore:begin:publicnames // want "unused //ignore directive"
+var quiet int
+
+//ignore:end
+
+//ignore:begin:publicnames
+var Mismatched int // want "Mismatched is public"
+
+//ignore:end:numberednames // want "does not match //ignore:begin on line 36"
+
+//ignore:begin:publicnames/"^Pattern"
+var Pattern int // want "Pattern is public"
+
+//ignore:end:publicnames/"^Other" // want "does not match //ignore:begin on line 41"
+
+//ignore:end // want "//ignore:end without matching //ignore:begin"
+
+//ignore:begin:numberednames // want "//ignore:begin without matching //ignore:end"
+var unclosed1 int // want "unclosed1 has numbers"
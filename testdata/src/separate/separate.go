@@ -0,0 +1,5 @@
+package separate
+
+var item1, item2 int
+
+func Public() {}
@@ -0,0 +1,6 @@
+package subtests
+
+//ignore:numberednames
+func Unused() {}
+
+var item1 int
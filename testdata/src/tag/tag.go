@@ -0,0 +1,7 @@
+package tag
+
+func Exported() {} // want `^\[publicnames\] Exported is public$`
+
+var value1 int // want `^\[numberednames\] value1 has numbers$`
+
+func unused() {} //ignore:publicnames // want `^unused //ignore directive$`
@@ -0,0 +1,15 @@
+package unknown // want "dependent analyzer ran"
+
+// Only dependent is run directly, and publicnames is its requirement.
+
+//ignore:publicname // want `unknown analyzer "publicname" in //ignore directive; did you mean "publicnames"\?`
+func PublicFunc() {} // want "PublicFunc is public"
+
+//ignore:publicnames,bogus // want `unknown analyzer "bogus" in //ignore directive$`
+func AnotherPublic() {}
+
+//ignore:all
+func AllPublic() {}
+
+//nolint:gocritic
+var nolinted int
@@ -0,0 +1,15 @@
+package until
+
+//ignore:publicnames until=2026-06-15
+func PublicFunc() {}
+
+//ignore:publicnames until=2026-06-14 // want "//ignore directive expired on 2026-06-14"
+func ExpiredFunc() {} // want "ExpiredFunc is public"
+
+//ignore:publicnames until=2026-12-31 -- pending API review
+func ReasonedFunc() {}
+
+//ignore:publicnames until=2026-02-30 // want "invalid //ignore directive: bad until date"
+func InvalidFunc() {} // want "InvalidFunc is public"
+
+func InlineFunc() {} // Legacy. //ignore:publicnames until=2026-01-01 // want "expired on 2026-01-01" "InlineFunc is public"
@@ -0,0 +1,25 @@
+package unused
+
+func goodFunc() {}
+
+//ignore:publicnames // want "unused //ignore directive"
+func quietFunc() {}
+
+//ignore:publicnames
+func PublicFunc() {}
+
+//ignore:all // want "unused //ignore directive"
+var quietVar int
+
+//ignore:publicnames,numberednames
+var count1 int
+
+//ignore:publicnames,othernames // want `unknown analyzer "othernames"`
+var quietVar2 int // numberednames is reported, but othernames did not run. // want "quietVar2 has numbers"
+
+// Prose that mentions //ignore directives is not a directive.
+func proseFunc() {}
+
+//ignore:numberednames
+func Overlapping1() { //ignore:publicnames
+}
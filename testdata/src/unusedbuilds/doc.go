@@ -0,0 +1,4 @@
+// Package unusedbuilds has public names only in its tests.
+//
+//ignore:package:publicnames -- tests are public
+package unusedbuilds
@@ -0,0 +1,4 @@
+package unusedbuilds
+
+//ignore:publicnames
+func helper() {}
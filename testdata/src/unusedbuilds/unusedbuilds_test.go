@@ -0,0 +1,5 @@
+package unusedbuilds
+
+import "testing"
+
+func TestHelper(t *testing.T) { helper() }
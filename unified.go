@@ -0,0 +1,142 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change in
+// a unified diff.
+const contextLines = 3
+
+// unifiedDiff returns a unified diff between before and after, the content
+// of the named file before and after a change.
+func unifiedDiff(name, before, after string) string {
+	ops := diffLines(splitLines(before), splitLines(after))
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", name, name)
+	for i := 0; i < len(ops); {
+		// Find the next change, and the run of changes and short gaps
+		// between them that make up its hunk.
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		start, end := max(i-contextLines, 0), i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*contextLines {
+				break
+			}
+		}
+		end = min(end+contextLines, len(ops))
+		hunk := ops[start:end]
+		var oldLen, newLen int
+		for _, op := range hunk {
+			if op.kind != '+' {
+				oldLen++
+			}
+			if op.kind != '-' {
+				newLen++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n",
+			hunkRange(hunk[0].old, oldLen), hunkRange(hunk[0].new, newLen),
+		)
+		for _, op := range hunk {
+			b.WriteByte(op.kind)
+			b.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange formats the range of a hunk starting at line index i, counting
+// from 0, with n lines.
+func hunkRange(i, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", i)
+	}
+	return fmt.Sprintf("%d,%d", i+1, n)
+}
+
+// splitLines splits s into lines, each keeping its newline.
+func splitLines(s string) []string {
+	var lines []string
+	for line := range strings.Lines(s) {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// A lineOp is a line of a diff: unchanged (' '), removed ('-') or added
+// ('+'). The old and new fields are the indices, counting from 0, of the
+// line in the old and new content, or of the line it precedes.
+type lineOp struct {
+	kind     byte
+	line     string
+	old, new int
+}
+
+// diffLines returns the edit script that turns a into b, using the longest
+// common subsequence of the lines that remain after their common prefix and
+// suffix are removed.
+func diffLines(a, b []string) []lineOp {
+	var prefix, suffix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// ma[i:] and mb[j:].
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []lineOp
+	for k := range prefix {
+		ops = append(ops, lineOp{' ', a[k], k, k})
+	}
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			ops = append(ops, lineOp{' ', ma[i], prefix + i, prefix + j})
+			i, j = i+1, j+1
+		case i < len(ma) && (j == len(mb) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, lineOp{'-', ma[i], prefix + i, prefix + j})
+			i++
+		default:
+			ops = append(ops, lineOp{'+', mb[j], prefix + i, prefix + j})
+			j++
+		}
+	}
+	for k := range suffix {
+		ops = append(ops, lineOp{' ', a[len(a)-suffix+k],
+			len(a) - suffix + k, len(b) - suffix + k,
+		})
+	}
+	return ops
+}